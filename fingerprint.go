@@ -0,0 +1,148 @@
+package meh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// FingerprintFrames is the maximum number of in-app stack frames that are
+// included in Fingerprint.
+const FingerprintFrames = 3
+
+// mehPackage is the package path of this package. Its frames are skipped when
+// looking for in-app frames in Fingerprint.
+const mehPackage = "github.com/lefinal/meh"
+
+// Fingerprint returns a stable hash for the kind of the given error that can be
+// used for grouping and deduplicating occurrences. It is built from the Code
+// and Message of each level, the type of non-meh errors and the top in-app
+// frames of the deepest stack trace (see ApplyStackTrace). Details as well as
+// messages of non-meh errors are not included as they usually contain varying
+// values like IDs. Levels with ErrNeutral and no message, like the ones added
+// by ApplyDetails, are skipped, too.
+func Fingerprint(err error) string {
+	h := sha256.New()
+	for it := NewErrorUnwrapper(err); it.Next(); {
+		e, ok := it.Current().(*Error)
+		if !ok {
+			// Messages of foreign errors often contain interpolated values, so we only
+			// use the type.
+			_, _ = fmt.Fprintf(h, "type:%T\n", it.Current())
+			continue
+		}
+		if e.Code == ErrNeutral && e.Message == "" {
+			continue
+		}
+		_, _ = fmt.Fprintf(h, "code:%s\nmessage:%s\n", e.Code, e.Message)
+	}
+	for _, frame := range fingerprintFrames(err) {
+		_, _ = fmt.Fprintf(h, "frame:%s\n", frame)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// fingerprintFrames returns the function names of the top in-app frames of the
+// deepest stack trace of the given error. Only frames of packages considered
+// in-app by IsInAppPackage are used. Line numbers are not included as they
+// change with unrelated code changes.
+func fingerprintFrames(err error) []string {
+	e, ok := err.(*Error)
+	if !ok {
+		return nil
+	}
+	frames := make([]string, 0, FingerprintFrames)
+	for _, frame := range e.StackTrace() {
+		if len(frames) == FingerprintFrames {
+			break
+		}
+		fn := runtime.FuncForPC(uintptr(frame) - 1)
+		if fn == nil {
+			continue
+		}
+		name := fn.Name()
+		if !isInAppFunc(name) {
+			continue
+		}
+		frames = append(frames, name)
+	}
+	return frames
+}
+
+var (
+	// inAppPrefixes are the package path prefixes of in-app functions.
+	inAppPrefixes = defaultInAppPrefixes()
+	// inAppPrefixesMutex locks inAppPrefixes.
+	inAppPrefixesMutex sync.RWMutex
+)
+
+// defaultInAppPrefixes returns the path of the main module from the build
+// information as default for SetInAppPrefixes.
+func defaultInAppPrefixes() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "" {
+		return nil
+	}
+	return []string{info.Main.Path}
+}
+
+// SetInAppPrefixes sets the package path prefixes like "github.com/acme/shop"
+// of functions that are considered in-app in Fingerprint and integrations like
+// mehsentry. Per default, the path of the main module from the build
+// information is used. Functions of package main are always in-app.
+func SetInAppPrefixes(prefixes ...string) {
+	inAppPrefixesMutex.Lock()
+	defer inAppPrefixesMutex.Unlock()
+	inAppPrefixes = append([]string(nil), prefixes...)
+}
+
+// InAppPrefixes returns a copy of the package path prefixes set via
+// SetInAppPrefixes.
+func InAppPrefixes() []string {
+	inAppPrefixesMutex.RLock()
+	defer inAppPrefixesMutex.RUnlock()
+	return append([]string(nil), inAppPrefixes...)
+}
+
+// isInAppFunc checks whether the function with the given fully qualified name
+// is considered in-app using IsInAppPackage.
+func isInAppFunc(name string) bool {
+	return IsInAppPackage(FuncPackage(name))
+}
+
+// IsInAppPackage checks whether functions of the package with the given path
+// are considered in-app. This is the case for package main and packages
+// matching a prefix set via SetInAppPrefixes, except for this package itself.
+func IsInAppPackage(pkg string) bool {
+	if pkg == mehPackage {
+		return false
+	}
+	if pkg == "main" {
+		return true
+	}
+	inAppPrefixesMutex.RLock()
+	defer inAppPrefixesMutex.RUnlock()
+	for _, prefix := range inAppPrefixes {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// FuncPackage returns the package path of the function with the given fully
+// qualified name like github.com/lefinal/meh.Wrap or
+// github.com/lefinal/meh.(*Error).Error as returned by runtime.Func.Name. If
+// the name holds no package, an empty string is returned.
+func FuncPackage(name string) string {
+	lastSlash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[lastSlash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	return name[:lastSlash+1+dot]
+}
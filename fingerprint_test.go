@@ -0,0 +1,88 @@
+package meh
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+// FingerprintSuite tests Fingerprint.
+type FingerprintSuite struct {
+	suite.Suite
+}
+
+func (suite *FingerprintSuite) TestStable() {
+	gen := func() error {
+		return Wrap(NewNotFoundErr("get user", nil), "handle request", nil)
+	}
+	suite.Equal(Fingerprint(gen()), Fingerprint(gen()), "should be stable")
+}
+
+func (suite *FingerprintSuite) TestIgnoreDetails() {
+	gen := func(userID int) error {
+		return Wrap(NewNotFoundErr("get user", Details{"user_id": userID}), "handle request", nil)
+	}
+	suite.Equal(Fingerprint(gen(1)), Fingerprint(gen(2)), "should ignore details")
+}
+
+func (suite *FingerprintSuite) TestIgnoreForeignMessages() {
+	gen := func(userID int) error {
+		return NewInternalErrFromErr(fmt.Errorf("user %d not found", userID), "get user", nil)
+	}
+	suite.Equal(Fingerprint(gen(1)), Fingerprint(gen(2)), "should ignore messages of foreign errors")
+}
+
+func (suite *FingerprintSuite) TestIgnoreApplyDetails() {
+	e := NewNotFoundErr("get user", nil)
+	suite.Equal(Fingerprint(e), Fingerprint(ApplyDetails(e, Details{"a": "b"})), "should ignore detail levels")
+}
+
+func (suite *FingerprintSuite) TestDifferentCodes() {
+	suite.NotEqual(Fingerprint(NewNotFoundErr("get user", nil)), Fingerprint(NewInternalErr("get user", nil)),
+		"should differ for different codes")
+}
+
+func (suite *FingerprintSuite) TestDifferentMessages() {
+	suite.NotEqual(Fingerprint(NewNotFoundErr("get user", nil)), Fingerprint(NewNotFoundErr("get group", nil)),
+		"should differ for different messages")
+}
+
+func (suite *FingerprintSuite) TestDifferentForeignTypes() {
+	suite.NotEqual(Fingerprint(NewInternalErrFromErr(errors.New("sad life"), "", nil)),
+		Fingerprint(NewInternalErrFromErr(fmt.Errorf("sad life: %w", errors.New("")), "", nil)),
+		"should differ for different types of foreign errors")
+}
+
+func (suite *FingerprintSuite) TestNil() {
+	suite.NotEmpty(Fingerprint(nil), "should return fingerprint for nil")
+}
+
+func TestFingerprint(t *testing.T) {
+	suite.Run(t, new(FingerprintSuite))
+}
+
+// TestIsInAppPackage tests IsInAppPackage and SetInAppPrefixes.
+func TestIsInAppPackage(t *testing.T) {
+	defer SetInAppPrefixes(InAppPrefixes()...)
+	SetInAppPrefixes("example.com/app")
+	assert.Equal(t, []string{"example.com/app"}, InAppPrefixes(), "should return set prefixes")
+	assert.False(t, IsInAppPackage("github.com/lefinal/meh"), "should skip meh")
+	assert.False(t, IsInAppPackage("runtime"), "should skip stdlib")
+	assert.False(t, IsInAppPackage("net/http"), "should skip stdlib with path")
+	assert.False(t, IsInAppPackage("github.com/jackc/pgx/v5"), "should skip dependencies")
+	assert.False(t, IsInAppPackage("example.com/application"), "should only match complete elements")
+	assert.True(t, IsInAppPackage("main"), "should include main")
+	assert.True(t, IsInAppPackage("example.com/app"), "should include app")
+	assert.True(t, IsInAppPackage("example.com/app/store"), "should include app packages")
+}
+
+// TestFuncPackage tests FuncPackage.
+func TestFuncPackage(t *testing.T) {
+	assert.Equal(t, "github.com/lefinal/meh", FuncPackage("github.com/lefinal/meh.Wrap"))
+	assert.Equal(t, "github.com/lefinal/meh", FuncPackage("github.com/lefinal/meh.(*Error).Error"))
+	assert.Equal(t, "example.com/app/store", FuncPackage("example.com/app/store.(*Store).User.func1"))
+	assert.Equal(t, "runtime", FuncPackage("runtime.goexit"))
+	assert.Equal(t, "", FuncPackage("goexit"))
+}
@@ -88,3 +88,36 @@ func NewForbiddenErr(message string, details Details) error {
 func NewForbiddenErrFromErr(err error, message string, details Details) error {
 	return NewErrFromErr(err, ErrForbidden, message, details)
 }
+
+// NewConflictErr creates a new ErrConflict with the given message and details.
+func NewConflictErr(message string, details Details) error {
+	return NewErr(ErrConflict, message, details)
+}
+
+// NewConflictErrFromErr creates a new ErrConflict with the given error to be wrapped,
+// message and details.
+func NewConflictErrFromErr(err error, message string, details Details) error {
+	return NewErrFromErr(err, ErrConflict, message, details)
+}
+
+// NewCanceledErr creates a new ErrCanceled with the given message and details.
+func NewCanceledErr(message string, details Details) error {
+	return NewErr(ErrCanceled, message, details)
+}
+
+// NewCanceledErrFromErr creates a new ErrCanceled with the given error to be wrapped,
+// message and details.
+func NewCanceledErrFromErr(err error, message string, details Details) error {
+	return NewErrFromErr(err, ErrCanceled, message, details)
+}
+
+// NewUnavailableErr creates a new ErrUnavailable with the given message and details.
+func NewUnavailableErr(message string, details Details) error {
+	return NewErr(ErrUnavailable, message, details)
+}
+
+// NewUnavailableErrFromErr creates a new ErrUnavailable with the given error to be wrapped,
+// message and details.
+func NewUnavailableErrFromErr(err error, message string, details Details) error {
+	return NewErrFromErr(err, ErrUnavailable, message, details)
+}
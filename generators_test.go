@@ -215,3 +215,129 @@ func (suite *NewForbiddenErrFromErrSuite) TestOK() {
 func TestNewForbiddenErrFromErr(t *testing.T) {
 	suite.Run(t, new(NewForbiddenErrFromErrSuite))
 }
+
+// NewConflictErrSuite tests NewConflictErr.
+type NewConflictErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewConflictErrSuite) TestOK() {
+	message := "Hello World!"
+	details := Details{"hello": "world"}
+
+	err := NewConflictErr(message, details).(*Error)
+
+	suite.Equal(ErrConflict, err.Code, "should have set correct error code")
+	suite.Equal(message, err.Message, "should have applied message")
+	suite.Equal(details, err.Details, "should have applied details")
+}
+
+func TestNewConflictErr(t *testing.T) {
+	suite.Run(t, new(NewConflictErrSuite))
+}
+
+// NewConflictErrFromErrSuite tests NewConflictErrFromErr.
+type NewConflictErrFromErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewConflictErrFromErrSuite) TestOK() {
+	originalErr := errors.New("yo")
+	message := "Hello World!"
+	details := Details{"hello": "world"}
+
+	err := NewConflictErrFromErr(originalErr, message, details).(*Error)
+
+	suite.Equal(ErrConflict, err.Code, "should have set correct error code")
+	suite.Equal(originalErr, err.WrappedErr, "should have applied the original error")
+	suite.Equal(message, err.Message, "should have applied message")
+	suite.Equal(details, err.Details, "should have applied details")
+}
+
+func TestNewConflictErrFromErr(t *testing.T) {
+	suite.Run(t, new(NewConflictErrFromErrSuite))
+}
+
+// NewCanceledErrSuite tests NewCanceledErr.
+type NewCanceledErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewCanceledErrSuite) TestOK() {
+	message := "Hello World!"
+	details := Details{"hello": "world"}
+
+	err := NewCanceledErr(message, details).(*Error)
+
+	suite.Equal(ErrCanceled, err.Code, "should have set correct error code")
+	suite.Equal(message, err.Message, "should have applied message")
+	suite.Equal(details, err.Details, "should have applied details")
+}
+
+func TestNewCanceledErr(t *testing.T) {
+	suite.Run(t, new(NewCanceledErrSuite))
+}
+
+// NewCanceledErrFromErrSuite tests NewCanceledErrFromErr.
+type NewCanceledErrFromErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewCanceledErrFromErrSuite) TestOK() {
+	originalErr := errors.New("yo")
+	message := "Hello World!"
+	details := Details{"hello": "world"}
+
+	err := NewCanceledErrFromErr(originalErr, message, details).(*Error)
+
+	suite.Equal(ErrCanceled, err.Code, "should have set correct error code")
+	suite.Equal(originalErr, err.WrappedErr, "should have applied the original error")
+	suite.Equal(message, err.Message, "should have applied message")
+	suite.Equal(details, err.Details, "should have applied details")
+}
+
+func TestNewCanceledErrFromErr(t *testing.T) {
+	suite.Run(t, new(NewCanceledErrFromErrSuite))
+}
+
+// NewUnavailableErrSuite tests NewUnavailableErr.
+type NewUnavailableErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewUnavailableErrSuite) TestOK() {
+	message := "Hello World!"
+	details := Details{"hello": "world"}
+
+	err := NewUnavailableErr(message, details).(*Error)
+
+	suite.Equal(ErrUnavailable, err.Code, "should have set correct error code")
+	suite.Equal(message, err.Message, "should have applied message")
+	suite.Equal(details, err.Details, "should have applied details")
+}
+
+func TestNewUnavailableErr(t *testing.T) {
+	suite.Run(t, new(NewUnavailableErrSuite))
+}
+
+// NewUnavailableErrFromErrSuite tests NewUnavailableErrFromErr.
+type NewUnavailableErrFromErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewUnavailableErrFromErrSuite) TestOK() {
+	originalErr := errors.New("yo")
+	message := "Hello World!"
+	details := Details{"hello": "world"}
+
+	err := NewUnavailableErrFromErr(originalErr, message, details).(*Error)
+
+	suite.Equal(ErrUnavailable, err.Code, "should have set correct error code")
+	suite.Equal(originalErr, err.WrappedErr, "should have applied the original error")
+	suite.Equal(message, err.Message, "should have applied message")
+	suite.Equal(details, err.Details, "should have applied details")
+}
+
+func TestNewUnavailableErrFromErr(t *testing.T) {
+	suite.Run(t, new(NewUnavailableErrFromErrSuite))
+}
@@ -0,0 +1,379 @@
+package meh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// DetailKeyTruncatedDetails is the details key for the number of details that
+// were dropped because of Limits.MaxDetails or Limits.MaxTotalSize.
+const DetailKeyTruncatedDetails = "_truncated_details"
+
+// Limits are size limits for details and messages that are applied in ToMap,
+// Error.MarshalJSON and Error.MarshalLogObject. Truncated data is marked, so
+// that readers know that data was cut. Zero values disable the respective
+// limit. Details with keys DetailKeyViolations and DetailKeyPublicMessage are
+// never truncated or dropped, so that Violations and PublicMessageOf keep
+// working.
+type Limits struct {
+	// MaxDetails is the maximum number of details per level. Details are kept in
+	// sorted order of keys and the number of dropped ones is added with key
+	// DetailKeyTruncatedDetails.
+	MaxDetails int
+	// MaxValueSize is the maximum size of a single details value in bytes when
+	// serialized as JSON. Larger values are replaced with their truncated JSON
+	// representation as string. The truncation marker counts towards the size.
+	MaxValueSize int
+	// MaxStringLength is the maximum number of characters of strings in details.
+	// Byte slices are limited based on their base64 representation.
+	MaxStringLength int
+	// MaxSliceLength is the maximum number of elements of slices, arrays and
+	// maps nested in details values.
+	MaxSliceLength int
+	// MaxDepth is the maximum nesting depth of slices, arrays, maps and structs
+	// in details values. Deeper ones are replaced with a marker.
+	MaxDepth int
+	// MaxTotalSize is the maximum size of all details in bytes when serialized
+	// as JSON. In ToMap, Error.MarshalJSON and Error.MarshalLogObject, it applies
+	// to all levels together and is spent from the deepest level up, as it
+	// usually holds the root cause. Details exceeding the size are dropped and
+	// counted with key DetailKeyTruncatedDetails.
+	MaxTotalSize int
+	// MaxMessageLength is the maximum number of characters of error messages.
+	MaxMessageLength int
+}
+
+// DefaultLimits returns the default Limits that keep log lines within sizes
+// accepted by usual log shippers.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxDetails:       100,
+		MaxValueSize:     16 * 1024,
+		MaxStringLength:  4096,
+		MaxSliceLength:   100,
+		MaxDepth:         8,
+		MaxTotalSize:     256 * 1024,
+		MaxMessageLength: 4096,
+	}
+}
+
+var (
+	// limits are the Limits to use in LimitDetails and LimitMessage.
+	limits = DefaultLimits()
+	// limitsMutex locks limits.
+	limitsMutex sync.RWMutex
+)
+
+// SetLimits sets the Limits that are used in LimitDetails and LimitMessage.
+// Per default, DefaultLimits are used. Pass an empty Limits in order to
+// disable all limits.
+func SetLimits(l Limits) {
+	limitsMutex.Lock()
+	defer limitsMutex.Unlock()
+	limits = l
+}
+
+// currentLimits returns the Limits set via SetLimits.
+func currentLimits() Limits {
+	limitsMutex.RLock()
+	defer limitsMutex.RUnlock()
+	return limits
+}
+
+// LimitDetails returns a copy of the given Details with the Limits set via
+// SetLimits applied. The given Details are not altered.
+func LimitDetails(details Details) Details {
+	l := currentLimits()
+	budget := l.MaxTotalSize
+	return l.limitDetails(details, &budget)
+}
+
+// LimitMessage truncates the given message based on Limits.MaxMessageLength
+// set via SetLimits.
+func LimitMessage(message string) string {
+	return truncateString(message, currentLimits().MaxMessageLength)
+}
+
+// prepareDetails redacts the given Details using RedactDetails and applies
+// Limits with the given remaining total size budget. If the budget is nil, a
+// new one is used.
+func prepareDetails(details Details, budget *int) Details {
+	l := currentLimits()
+	if budget == nil {
+		b := l.MaxTotalSize
+		budget = &b
+	}
+	return l.limitDetails(RedactDetails(details), budget)
+}
+
+// limitDetails applies the Limits to the given Details. The serialized size is
+// subtracted from the given budget.
+func (l Limits) limitDetails(details Details, budget *int) Details {
+	if details == nil {
+		return nil
+	}
+	if l == (Limits{}) {
+		return details
+	}
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	// Keep structured details first, so that they are not dropped.
+	sort.Slice(keys, func(i, j int) bool {
+		if isStructuredDetail(keys[i]) != isStructuredDetail(keys[j]) {
+			return isStructuredDetail(keys[i])
+		}
+		return keys[i] < keys[j]
+	})
+	dropped := 0
+	if l.MaxDetails > 0 && len(keys) > l.MaxDetails {
+		dropped = len(keys) - l.MaxDetails
+		keys = keys[:l.MaxDetails]
+	}
+	limited := make(Details, len(keys))
+	for _, k := range keys {
+		var v any
+		var size int
+		if isStructuredDetail(k) {
+			v = details[k]
+			raw, _ := json.Marshal(v)
+			size = len(raw)
+		} else {
+			v, size = l.limitValue(details[k])
+		}
+		if l.MaxTotalSize > 0 {
+			size += len(k)
+			if size > *budget && !isStructuredDetail(k) {
+				dropped++
+				continue
+			}
+			*budget -= size
+		}
+		limited[k] = v
+	}
+	if dropped > 0 {
+		limited[DetailKeyTruncatedDetails] = dropped
+	}
+	return limited
+}
+
+// isStructuredDetail checks whether the details with the given key are read
+// back by meh and must therefore keep their type.
+func isStructuredDetail(key string) bool {
+	return key == DetailKeyViolations || key == DetailKeyPublicMessage
+}
+
+// limitValue applies the Limits to the given details value. It returns the
+// limited value and its serialized size.
+func (l Limits) limitValue(v any) (any, int) {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, len(fmt.Sprint(v))
+	case string:
+		v = truncateString(v, l.MaxStringLength)
+		if l.MaxValueSize > 0 && len(v)+2 > l.MaxValueSize {
+			return truncateJSONString(v, l.MaxValueSize)
+		}
+		return v, len(v) + 2
+	case []byte:
+		// Byte slices are marshalled as base64 string.
+		size := base64.StdEncoding.EncodedLen(len(v))
+		if (l.MaxStringLength <= 0 || size <= l.MaxStringLength) && (l.MaxValueSize <= 0 || size+2 <= l.MaxValueSize) {
+			return v, size + 2
+		}
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		// We cannot measure the value, so we keep it.
+		return v, 0
+	}
+	if l.mightExceedGeneric(raw) {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var generic any
+		if decoder.Decode(&generic) == nil {
+			if limited, changed := l.limitGeneric(generic, 0); changed {
+				v = limited
+				raw, _ = json.Marshal(limited)
+			}
+		}
+	}
+	if l.MaxValueSize > 0 && len(raw) > l.MaxValueSize {
+		return truncateJSONString(string(raw), l.MaxValueSize)
+	}
+	return v, len(raw)
+}
+
+// mightExceedGeneric checks whether the given serialized value might exceed
+// Limits.MaxStringLength, Limits.MaxSliceLength or Limits.MaxDepth. If not,
+// decoding it for limitGeneric can be skipped.
+func (l Limits) mightExceedGeneric(raw []byte) bool {
+	// A string exceeding the length needs at least one byte per character and
+	// quotes.
+	if l.MaxStringLength > 0 && len(raw) > l.MaxStringLength+2 {
+		return true
+	}
+	if l.MaxSliceLength <= 0 && l.MaxDepth <= 0 {
+		return false
+	}
+	depth, elements := scanJSONNesting(raw)
+	return (l.MaxSliceLength > 0 && elements > l.MaxSliceLength) || (l.MaxDepth > 0 && depth > l.MaxDepth)
+}
+
+// scanJSONNesting returns the maximum nesting depth of arrays and objects in
+// the given JSON as well as the maximum number of elements or entries of a
+// single one without decoding it.
+func scanJSONNesting(raw []byte) (int, int) {
+	maxDepth := 0
+	maxElements := 0
+	// elements holds the number of elements for each open array or object.
+	elements := make([]int, 0)
+	inString := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
+			elements = append(elements, 0)
+			maxDepth = max(maxDepth, len(elements))
+			if i+1 < len(raw) && raw[i+1] != ']' && raw[i+1] != '}' {
+				elements[len(elements)-1] = 1
+			}
+			maxElements = max(maxElements, elements[len(elements)-1])
+		case ']', '}':
+			if len(elements) > 0 {
+				elements = elements[:len(elements)-1]
+			}
+		case ',':
+			if len(elements) > 0 {
+				elements[len(elements)-1]++
+				maxElements = max(maxElements, elements[len(elements)-1])
+			}
+		}
+	}
+	return maxDepth, maxElements
+}
+
+// limitGeneric applies the Limits to the given value that was unmarshalled from
+// JSON and is found at the given depth. If nothing was changed, false is
+// returned.
+func (l Limits) limitGeneric(v any, depth int) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		truncated := truncateString(v, l.MaxStringLength)
+		return truncated, truncated != v
+	case []any:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return "... (max depth reached)", true
+		}
+		changed := false
+		if l.MaxSliceLength > 0 && len(v) > l.MaxSliceLength {
+			v = append(v[:l.MaxSliceLength:l.MaxSliceLength], fmt.Sprintf("... (%d more elements)", len(v)-l.MaxSliceLength))
+			changed = true
+		}
+		for i := range v {
+			var elementChanged bool
+			v[i], elementChanged = l.limitGeneric(v[i], depth+1)
+			changed = changed || elementChanged
+		}
+		return v, changed
+	case map[string]any:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return "... (max depth reached)", true
+		}
+		changed := false
+		if l.MaxSliceLength > 0 && len(v) > l.MaxSliceLength {
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys[l.MaxSliceLength:] {
+				delete(v, k)
+			}
+			v["..."] = fmt.Sprintf("(%d more entries)", len(keys)-l.MaxSliceLength)
+			changed = true
+		}
+		for k, element := range v {
+			limited, elementChanged := l.limitGeneric(element, depth+1)
+			v[k] = limited
+			changed = changed || elementChanged
+		}
+		return v, changed
+	}
+	return v, false
+}
+
+// truncateJSONString truncates the given string with truncateBytes, so that
+// its JSON representation holds at most the given number of bytes. It returns
+// the truncated string and the size of its JSON representation.
+func truncateJSONString(s string, max int) (string, int) {
+	// Escaping might make the JSON representation larger than the string, so we
+	// shrink until it fits.
+	limit := max - 2
+	for {
+		truncated := truncateBytes(s, limit)
+		raw, _ := json.Marshal(truncated)
+		if len(raw) <= max || limit <= 0 {
+			return truncated, len(raw)
+		}
+		limit -= len(raw) - max
+	}
+}
+
+// truncateBytes truncates the given string to at most the given number of
+// bytes without splitting characters. A marker with the number of cut bytes is
+// added and counts towards the limit. If the limit is too small for the marker,
+// only "..." is added.
+func truncateBytes(s string, max int) string {
+	if max < 0 {
+		max = 0
+	}
+	if len(s) <= max {
+		return s
+	}
+	// The number of cut bytes has at most as many digits as the length of s.
+	marker := fmt.Sprintf("... (%d more bytes)", len(s))
+	shortMarker := len(marker) > max
+	if shortMarker {
+		marker = "..."
+	}
+	cut := max - len(marker)
+	if cut < 0 {
+		return marker[:max]
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if shortMarker {
+		return s[:cut] + marker
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", s[:cut], len(s)-cut)
+}
+
+// truncateString truncates the given string to the given number of characters
+// and adds a marker with the number of cut characters. If max is not positive,
+// the string is returned as is.
+func truncateString(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return fmt.Sprintf("%s... (%d more characters)", string(runes[:max]), len(runes)-max)
+}
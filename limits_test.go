@@ -0,0 +1,211 @@
+package meh
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+)
+
+// LimitsSuite tests SetLimits, LimitDetails and LimitMessage.
+type LimitsSuite struct {
+	suite.Suite
+}
+
+func (suite *LimitsSuite) TearDownTest() {
+	SetLimits(DefaultLimits())
+}
+
+func (suite *LimitsSuite) TestDefaultKeepsSmallValues() {
+	details := Details{
+		"user_id": 42,
+		"name":    "meh",
+		"ok":      true,
+		"tags":    []string{"a", "b"},
+		"nested":  Details{"a": 1},
+	}
+	suite.Equal(details, LimitDetails(details), "should keep original values")
+}
+
+func (suite *LimitsSuite) TestDisabled() {
+	SetLimits(Limits{})
+	long := strings.Repeat("a", 10000)
+	suite.Equal(Details{"long": long}, LimitDetails(Details{"long": long}))
+	suite.Equal(long, LimitMessage(long))
+}
+
+func (suite *LimitsSuite) TestMaxDetails() {
+	SetLimits(Limits{MaxDetails: 2})
+	suite.Equal(Details{"a": 1, "b": 2, DetailKeyTruncatedDetails: 2},
+		LimitDetails(Details{"d": 4, "c": 3, "b": 2, "a": 1}))
+}
+
+func (suite *LimitsSuite) TestMaxStringLength() {
+	SetLimits(Limits{MaxStringLength: 3})
+	suite.Equal(Details{
+		"short":  "abc",
+		"long":   "äöü... (2 more characters)",
+		"nested": map[string]any{"long": "abc... (1 more characters)"},
+	}, LimitDetails(Details{
+		"short":  "abc",
+		"long":   "äöüßx",
+		"nested": map[string]string{"long": "abcd"},
+	}))
+}
+
+func (suite *LimitsSuite) TestMaxSliceLength() {
+	SetLimits(Limits{MaxSliceLength: 2})
+	limited := LimitDetails(Details{
+		"slice": []int{1, 2, 3, 4},
+		"map":   map[string]int{"a": 1, "b": 2, "c": 3},
+	})
+	suite.Equal([]any{json.Number("1"), json.Number("2"), "... (2 more elements)"}, limited["slice"])
+	suite.Equal(map[string]any{"a": json.Number("1"), "b": json.Number("2"), "...": "(1 more entries)"}, limited["map"])
+}
+
+func (suite *LimitsSuite) TestMaxDepth() {
+	SetLimits(Limits{MaxDepth: 2})
+	type inner struct {
+		Values []int `json:"values"`
+	}
+	type outer struct {
+		Inner inner `json:"inner"`
+	}
+	suite.Equal(Details{
+		"deep": map[string]any{"inner": map[string]any{"values": "... (max depth reached)"}},
+	}, LimitDetails(Details{"deep": outer{Inner: inner{Values: []int{1}}}}))
+}
+
+func (suite *LimitsSuite) TestMaxValueSize() {
+	SetLimits(Limits{MaxValueSize: 32})
+	limited := LimitDetails(Details{"body": map[string]string{"a": "bcdefghijklmnopqrstuvwxyz"}})
+	suite.Equal(Details{
+		"body": `{"a":"bc... (25 more bytes)`,
+	}, limited)
+	raw, err := json.Marshal(limited["body"])
+	suite.Require().NoError(err, "marshal should not fail")
+	suite.LessOrEqual(len(raw), 32, "should count marker and escaping towards size")
+}
+
+func (suite *LimitsSuite) TestMaxTotalSize() {
+	SetLimits(Limits{MaxTotalSize: 10})
+	suite.Equal(Details{"a": "abc", DetailKeyTruncatedDetails: 1},
+		LimitDetails(Details{"a": "abc", "b": "defghi"}))
+}
+
+func (suite *LimitsSuite) TestMaxMessageLength() {
+	SetLimits(Limits{MaxMessageLength: 4})
+	suite.Equal("long... (3 more characters)", LimitMessage("longest"))
+}
+
+func (suite *LimitsSuite) TestToMapSharesTotalSize() {
+	SetLimits(Limits{MaxTotalSize: 10})
+	m := ToMap(Wrap(NewInternalErr("meh", Details{"b": "defghi"}), "wrap", Details{"a": "abc"}))
+	suite.Equal("defghi", m["1/b"], "should keep details of deepest level")
+	suite.NotContains(m, "0/a", "should drop details exceeding total size")
+	suite.Equal(1, m["0/"+DetailKeyTruncatedDetails])
+}
+
+func (suite *LimitsSuite) TestKeepsViolations() {
+	SetLimits(Limits{MaxSliceLength: 1, MaxDepth: 1, MaxValueSize: 10, MaxTotalSize: 10, MaxDetails: 1})
+	err := NewValidationError().
+		Add("a", "required", "", nil).
+		Add("b", "max", "", map[string]any{"max": 1}).
+		Err()
+	raw, marshalErr := json.Marshal(err)
+	suite.Require().NoError(marshalErr, "marshal should not fail")
+	var unmarshalled *Error
+	suite.Require().NoError(json.Unmarshal(raw, &unmarshalled), "unmarshal should not fail")
+	suite.Len(Violations(unmarshalled), 2, "should keep violations")
+}
+
+func (suite *LimitsSuite) TestKeepsPublicMessage() {
+	SetLimits(Limits{MaxDepth: 1, MaxValueSize: 10})
+	err := WithPublicMessage(NewBadInputErr("meh", nil), "invalid_name", map[string]any{"name": "meh"})
+	raw, marshalErr := json.Marshal(err)
+	suite.Require().NoError(marshalErr, "marshal should not fail")
+	var unmarshalled *Error
+	suite.Require().NoError(json.Unmarshal(raw, &unmarshalled), "unmarshal should not fail")
+	message, ok := PublicMessageOf(unmarshalled)
+	suite.Require().True(ok, "should find public message")
+	suite.Equal("invalid_name", message.ID)
+}
+
+func (suite *LimitsSuite) TestStringMaxValueSize() {
+	SetLimits(Limits{MaxValueSize: 30})
+	suite.Equal(Details{"s": "abcdefghi... (23 more bytes)", "b": []byte("ab")},
+		LimitDetails(Details{"s": "abcdefghijklmnopqrstuvwxyz012345", "b": []byte("ab")}))
+	SetLimits(Limits{MaxValueSize: 10})
+	suite.Equal(Details{"s": "abcde..."}, LimitDetails(Details{"s": "abcdefghijklmnopqrstuvwxyz012345"}),
+		"should use short marker if the limit is too small")
+}
+
+func (suite *LimitsSuite) TestToMapMessage() {
+	SetLimits(Limits{MaxMessageLength: 4})
+	suite.Equal("wrap... (5 more characters)", ToMap(Wrap(NewInternalErr("meh", nil), "wrap", nil))[MapFieldErrorMessage])
+}
+
+func (suite *LimitsSuite) TestMarshalJSON() {
+	SetLimits(Limits{MaxStringLength: 3, MaxMessageLength: 3})
+	raw, err := json.Marshal(NewInternalErr("message", Details{"long": "abcdef"}))
+	suite.Require().NoError(err, "should not fail")
+	suite.Contains(string(raw), `"abc... (3 more characters)"`)
+	suite.Contains(string(raw), `"mes... (4 more characters)"`)
+}
+
+func (suite *LimitsSuite) TestMarshalLogObject() {
+	SetLimits(Limits{MaxStringLength: 3})
+	enc := zapcore.NewMapObjectEncoder()
+	suite.Require().NoError(Cast(NewInternalErr("meh", Details{"long": "abcdef"})).MarshalLogObject(enc))
+	levels := enc.Fields[LogObjectKeyLevels].([]any)
+	details := levels[0].(map[string]any)[LogObjectKeyDetails].(map[string]any)
+	suite.Equal("abc... (3 more characters)", details["long"])
+}
+
+func (suite *LimitsSuite) TestMarshalJSONSharesTotalSize() {
+	SetLimits(Limits{MaxTotalSize: 10})
+	raw, err := json.Marshal(Wrap(NewInternalErr("meh", Details{"b": "defghi"}), "wrap", Details{"a": "abc"}))
+	suite.Require().NoError(err, "should not fail")
+	suite.Contains(string(raw), `"b":"defghi"`, "should keep details of deepest level")
+	suite.NotContains(string(raw), `"a":"abc"`, "should drop details exceeding total size")
+}
+
+func (suite *LimitsSuite) TestMarshalLogObjectSharesTotalSize() {
+	SetLimits(Limits{MaxTotalSize: 10})
+	enc := zapcore.NewMapObjectEncoder()
+	err := Cast(Wrap(NewInternalErr("meh", Details{"b": "defghi"}), "wrap", Details{"a": "abc"}))
+	suite.Require().NoError(err.MarshalLogObject(enc))
+	levels := enc.Fields[LogObjectKeyLevels].([]any)
+	suite.Equal(map[string]any{DetailKeyTruncatedDetails: 1},
+		levels[0].(map[string]any)[LogObjectKeyDetails], "should drop details exceeding total size")
+	suite.Equal(map[string]any{"b": "defghi"},
+		levels[1].(map[string]any)[LogObjectKeyDetails], "should keep details of deepest level")
+}
+
+func TestLimits(t *testing.T) {
+	suite.Run(t, new(LimitsSuite))
+}
+
+// TestMightExceedGeneric assures that decoding is only done for values that
+// might actually exceed limits.
+func TestMightExceedGeneric(t *testing.T) {
+	l := DefaultLimits()
+	tests := []struct {
+		name   string
+		raw    string
+		expect bool
+	}{
+		{name: "flat", raw: `{"user_id":42,"name":"meh","active":true}`, expect: false},
+		{name: "brackets in strings", raw: `{"a":"[[[[[[[[[[[[,,,,"}`, expect: false},
+		{name: "deep", raw: strings.Repeat("[", 9) + strings.Repeat("]", 9), expect: true},
+		{name: "max depth", raw: strings.Repeat("[", 8) + strings.Repeat("]", 8), expect: false},
+		{name: "long slice", raw: "[" + strings.Repeat("1,", 100) + "1]", expect: true},
+		{name: "long string", raw: `"` + strings.Repeat("a", 4097) + `"`, expect: true},
+	}
+	for _, tt := range tests {
+		if got := l.mightExceedGeneric([]byte(tt.raw)); got != tt.expect {
+			t.Errorf("%s: expected %t but got %t", tt.name, tt.expect, got)
+		}
+	}
+}
@@ -30,6 +30,15 @@ const (
 	ErrUnauthorized Code = "unauthorized"
 	// ErrForbidden is used for unauthorized access to resources.
 	ErrForbidden Code = "forbidden"
+	// ErrConflict is used when a request conflicts with the current state of a
+	// resource, e.g. when creating an entity that already exists.
+	ErrConflict Code = "conflict"
+	// ErrCanceled is used when an operation was canceled, e.g. because the
+	// context.Context was done.
+	ErrCanceled Code = "canceled"
+	// ErrUnavailable is used when a required service or resource is temporarily
+	// unavailable, e.g. because of connection problems or exhausted resources.
+	ErrUnavailable Code = "unavailable"
 )
 
 // Details are optionally provided error details in Error.Details that are used
@@ -56,6 +65,11 @@ func genStackTrace(err error) StackTrace {
 // Error is the container for any relevant error information that needs to be
 // kept when bubbling. For wrapping errors use Wrap. You can create an Error
 // manually or by using generators like NewInternalErrFromErr.
+//
+// Functions of meh never alter given errors but return new levels instead.
+// Therefore, an Error can safely be shared, for example, as sentinel error or
+// between goroutines, as long as it is not altered after creation. Use
+// Error.Clone if you need to alter a shared one.
 type Error struct {
 	// Code is the type of Error.
 	//
@@ -88,33 +102,54 @@ type jsonError struct {
 	Trace                 StackTrace      `json:"trace"`
 }
 
-// MarshalJSON marshals the Error into a JSON representation.
+// MarshalJSON marshals the Error into a JSON representation. Details are
+// redacted using RedactDetails and Limits set via SetLimits are applied.
 func (e *Error) MarshalJSON() ([]byte, error) {
+	budget := currentLimits().MaxTotalSize
+	return e.marshalJSON(&budget)
+}
+
+// marshalJSON implements MarshalJSON with the given remaining total size budget
+// for details that is shared with wrapped errors. Wrapped errors are marshalled
+// first, so that the budget is spent from the deepest level up like in ToMap.
+func (e *Error) marshalJSON(budget *int) ([]byte, error) {
 	var err error
+	details := e.Details
+	// withNative returns a copy of the details with the native representation of
+	// the wrapped error. The original details are not altered as the Error might
+	// be shared.
+	withNative := func() Details {
+		copied := make(Details, len(e.Details)+1)
+		for k, v := range e.Details {
+			copied[k] = v
+		}
+		copied["_native"] = fmt.Sprintf("%+v", e.WrappedErr)
+		return copied
+	}
 	// Marshal wrapped error.
 	var wrappedErrJSON json.RawMessage
 	if e.WrappedErr != nil {
-		var wrappedErrToMarshal any = e.WrappedErr
-		if _, ok := e.WrappedErr.(*Error); !ok {
+		wrappedErrToMarshal, ok := e.WrappedErr.(*Error)
+		if !ok {
 			// No meh error. Marshal and parse again into details map.
 			wrappedErrToMarshalInMehRepresentation := &Error{
 				Message: e.WrappedErr.Error(),
 			}
 			if wrappedForeignErrJSON, err := json.Marshal(e.WrappedErr); err != nil {
 				// We cannot marshal the error. Fallback to native representation.
-				e.Details["_native"] = fmt.Sprintf("%+v", e.WrappedErr)
+				details = withNative()
 			} else {
 				err = json.Unmarshal(wrappedForeignErrJSON, &wrappedErrToMarshalInMehRepresentation.Details)
 				if err != nil {
 					// Some weird representation. We to the same as above and set the native
 					// representation.
-					e.Details["_native"] = fmt.Sprintf("%+v", e.WrappedErr)
+					details = withNative()
 				}
 			}
 			// Final representation of our foreign meh error to marshal is ready. Marshal it.
 			wrappedErrToMarshal = wrappedErrToMarshalInMehRepresentation
 		}
-		wrappedErrJSON, err = json.Marshal(wrappedErrToMarshal)
+		wrappedErrJSON, err = wrappedErrToMarshal.marshalJSON(budget)
 		if err != nil {
 			return nil, NewInternalErrFromErr(err, "marshal wrapped error", nil)
 		}
@@ -124,8 +159,8 @@ func (e *Error) MarshalJSON() ([]byte, error) {
 		Code:                  e.Code,
 		WrappedErr:            wrappedErrJSON,
 		WrappedErrPassThrough: e.WrappedErrPassThrough,
-		Message:               e.Message,
-		Details:               e.Details,
+		Message:               LimitMessage(e.Message),
+		Details:               prepareDetails(details, budget),
 		Trace:                 e.Trace,
 	}
 	return json.Marshal(eJSON)
@@ -201,11 +236,12 @@ func ErrorCode(err error) Code {
 	return ErrNeutral
 }
 
-// ApplyStackTrace applies the current stack trace to the given error.
+// ApplyStackTrace returns a copy of the highest level of the given error with
+// the current stack trace applied. The given error is not altered.
 func ApplyStackTrace(err error) error {
-	e := Cast(err)
+	e := *Cast(err)
 	e.Trace = genStackTrace(err)
-	return e
+	return &e
 }
 
 // stackTracer is the interface for providing an errors.StackTrace that is used
@@ -258,6 +294,62 @@ func ApplyDetails(err error, details Details) error {
 	}
 }
 
+// Clone returns a deep copy of the Error. All levels that are Error are copied
+// along with their Details and stack traces. Nested Details, maps and slices in
+// details are copied as well while other values are shared. Wrapped errors that
+// are no Error are shared as they cannot be copied.
+//
+// No function of meh alters given errors, so Clone is only required if you
+// want to alter an Error that might be shared.
+func (e *Error) Clone() *Error {
+	if e == nil {
+		return nil
+	}
+	cloned := *e
+	cloned.Details = cloneDetails(e.Details)
+	if e.Trace.StackTrace != nil {
+		cloned.Trace.StackTrace = append(errors.StackTrace(nil), e.Trace.StackTrace...)
+	}
+	if wrapped, ok := e.WrappedErr.(*Error); ok {
+		cloned.WrappedErr = wrapped.Clone()
+	}
+	return &cloned
+}
+
+// cloneDetails returns a deep copy of the given Details for Error.Clone.
+func cloneDetails(details Details) Details {
+	if details == nil {
+		return nil
+	}
+	cloned := make(Details, len(details))
+	for k, v := range details {
+		cloned[k] = cloneDetailValue(v)
+	}
+	return cloned
+}
+
+// cloneDetailValue returns a deep copy of the given details value if it is
+// Details, a map with string keys or a slice of any. Otherwise, the value is
+// returned as is.
+func cloneDetailValue(v any) any {
+	switch v := v.(type) {
+	case Details:
+		return cloneDetails(v)
+	case map[string]any:
+		return map[string]any(cloneDetails(v))
+	case []any:
+		if v == nil {
+			return v
+		}
+		cloned := make([]any, len(v))
+		for i, element := range v {
+			cloned[i] = cloneDetailValue(element)
+		}
+		return cloned
+	}
+	return v
+}
+
 // Cast tries to Cast the given error to *Error. In case of failure, a new
 // ErrUnexpected is created, wrapping the original error.
 func Cast(err error) *Error {
@@ -292,18 +384,26 @@ const (
 // ToMap returns the details of the given error as a key-value map with appended
 // enhanced information regarding the error itself (Error.Code to
 // MapFieldErrorCode and the Error.Error-message to MapFieldErrorMessage).
+// Details are redacted using RedactDetails and Limits set via SetLimits are
+// applied.
 func ToMap(err error) map[string]interface{} {
 	e := Cast(err)
 	m := make(map[string]interface{})
-	// First, we add all details from the highest level to the lowest one.
+	// First, we add all details. The total size budget is spent from the deepest
+	// level up, as it usually holds the root cause.
+	levels := make([]error, 0)
 	for it := NewErrorUnwrapper(err); it.Next(); {
-		for k, v := range Cast(it.Current()).Details {
-			m[fmt.Sprintf("%d/%s", it.Level(), k)] = v
+		levels = append(levels, it.Current())
+	}
+	budget := currentLimits().MaxTotalSize
+	for level := len(levels) - 1; level >= 0; level-- {
+		for k, v := range prepareDetails(Cast(levels[level]).Details, &budget) {
+			m[fmt.Sprintf("%d/%s", level, k)] = v
 		}
 	}
 	// Then we add all metadata.
 	m[MapFieldErrorCode] = ErrorCode(e)
-	m[MapFieldErrorMessage] = e.Error()
+	m[MapFieldErrorMessage] = LimitMessage(e.Error())
 	return m
 }
 
@@ -401,3 +401,84 @@ func (suite *ErrorMarshallingSuite) TestOK() {
 func TestErrorMarshalling(t *testing.T) {
 	suite.Run(t, new(ErrorMarshallingSuite))
 }
+
+// unmarshallableErr is an error that cannot be marshalled to JSON.
+type unmarshallableErr struct {
+	C chan int
+}
+
+func (e unmarshallableErr) Error() string {
+	return "unmarshallable"
+}
+
+func (suite *ErrorMarshallingSuite) TestNativeDoesNotAlterDetails() {
+	details := Details{"a": 1}
+	e := &Error{WrappedErr: unmarshallableErr{}, Details: details}
+	raw, err := json.Marshal(e)
+	suite.Require().NoError(err, "marshal should not fail")
+	suite.Contains(string(raw), "_native", "should add native representation")
+	suite.Equal(Details{"a": 1}, details, "should not alter details")
+}
+
+func (suite *ErrorMarshallingSuite) TestNativeWithoutDetails() {
+	_, err := json.Marshal(&Error{WrappedErr: unmarshallableErr{}})
+	suite.NoError(err, "marshal should not fail")
+}
+
+// ApplyStackTraceSuite tests ApplyStackTrace.
+type ApplyStackTraceSuite struct {
+	suite.Suite
+}
+
+func (suite *ApplyStackTraceSuite) TestDoesNotAlterError() {
+	original := NewNotFoundErr("meh", Details{"a": 1})
+	applied := ApplyStackTrace(original)
+	suite.Nil(Cast(original).Trace.StackTrace, "should not alter original")
+	suite.NotNil(Cast(applied).Trace.StackTrace, "should apply stack trace")
+	suite.Equal(ErrNotFound, ErrorCode(applied), "should keep code")
+	suite.Equal(original.Error(), applied.Error(), "should keep message")
+}
+
+func (suite *ApplyStackTraceSuite) TestForeign() {
+	applied := ApplyStackTrace(os.ErrNotExist)
+	suite.NotNil(Cast(applied).Trace.StackTrace, "should apply stack trace")
+	suite.Equal(ErrUnexpected, ErrorCode(applied), "should return unexpected error")
+}
+
+func TestApplyStackTrace(t *testing.T) {
+	suite.Run(t, new(ApplyStackTraceSuite))
+}
+
+// ErrorCloneSuite tests Error.Clone.
+type ErrorCloneSuite struct {
+	suite.Suite
+}
+
+func (suite *ErrorCloneSuite) TestNil() {
+	var e *Error
+	suite.Nil(e.Clone())
+}
+
+func (suite *ErrorCloneSuite) TestDeepCopy() {
+	original := Cast(Wrap(ApplyStackTrace(NewNotFoundErrFromErr(os.ErrNotExist, "inner", Details{
+		"nested": Details{"a": 1},
+		"list":   []any{map[string]any{"b": 2}},
+	})), "outer", Details{"c": 3}))
+	cloned := original.Clone()
+	suite.Equal(original, cloned, "should be equal")
+	inner := cloned.WrappedErr.(*Error)
+	cloned.Details["c"] = 4
+	inner.Details["nested"].(Details)["a"] = 5
+	inner.Details["list"].([]any)[0].(map[string]any)["b"] = 6
+	inner.Trace.StackTrace[0] = 0
+	originalInner := original.WrappedErr.(*Error)
+	suite.Equal(3, original.Details["c"], "should not alter original details")
+	suite.Equal(1, originalInner.Details["nested"].(Details)["a"], "should not alter nested details")
+	suite.Equal(2, originalInner.Details["list"].([]any)[0].(map[string]any)["b"], "should not alter nested slices")
+	suite.NotZero(originalInner.Trace.StackTrace[0], "should not alter stack trace")
+	suite.Same(os.ErrNotExist, inner.WrappedErr, "should share foreign errors")
+}
+
+func TestError_Clone(t *testing.T) {
+	suite.Run(t, new(ErrorCloneSuite))
+}
@@ -0,0 +1,257 @@
+// Package mehdoc generates documentation for meh.Code like a Markdown
+// reference, OpenAPI components and TypeScript types.
+package mehdoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// CodeDoc describes a meh.Code for documentation.
+type CodeDoc struct {
+	// Code is the documented meh.Code.
+	Code meh.Code
+	// Description is a human-readable description of when the Code is used.
+	Description string
+	// HTTPStatus is the HTTP status code that is responded for the Code. If not
+	// set, the Code is not listed in OpenAPI responses. Use
+	// ApplyHTTPStatusCodeMapping in order to set it from a
+	// mehhttp.HTTPStatusCodeMapper.
+	HTTPStatus int
+}
+
+// NativeCodes returns the CodeDoc list for the native codes of meh that are
+// used as effective error codes. meh.ErrUnexpected and meh.ErrNeutral are not
+// included.
+func NativeCodes() []CodeDoc {
+	return []CodeDoc{
+		{Code: meh.ErrInternal, Description: "An internal error occurred."},
+		{Code: meh.ErrBadInput, Description: "Submitted data was invalid."},
+		{Code: meh.ErrNotFound, Description: "The requested resource was not found."},
+		{Code: meh.ErrUnauthorized, Description: "The caller is not known but the resource requires authorized access."},
+		{Code: meh.ErrForbidden, Description: "The caller is not allowed to access the resource."},
+		{Code: meh.ErrConflict, Description: "The request conflicts with the current state of the resource."},
+		{Code: meh.ErrCanceled, Description: "The operation was canceled."},
+		{Code: meh.ErrUnavailable, Description: "A required service or resource is temporarily unavailable."},
+	}
+}
+
+// ApplyHTTPStatusCodeMapping returns a copy of the given CodeDoc list where
+// HTTPStatus is set using the given mehhttp.HTTPStatusCodeMapper for all
+// entries without HTTPStatus.
+func ApplyHTTPStatusCodeMapping(docs []CodeDoc, mapper mehhttp.HTTPStatusCodeMapper) []CodeDoc {
+	applied := make([]CodeDoc, 0, len(docs))
+	for _, doc := range docs {
+		if doc.HTTPStatus == 0 {
+			doc.HTTPStatus = mapper(doc.Code)
+		}
+		applied = append(applied, doc)
+	}
+	return applied
+}
+
+// sortedDocs returns a copy of the given CodeDoc list sorted by code. If a code
+// is empty or contained multiple times, an meh.ErrBadInput error is returned.
+func sortedDocs(docs []CodeDoc) ([]CodeDoc, error) {
+	sorted := append([]CodeDoc(nil), docs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Code < sorted[j].Code
+	})
+	for i, doc := range sorted {
+		if doc.Code == meh.ErrUnexpected {
+			return nil, meh.NewBadInputErr("empty code", meh.Details{"description": doc.Description})
+		}
+		if i > 0 && sorted[i-1].Code == doc.Code {
+			return nil, meh.NewBadInputErr("duplicate code", meh.Details{"code": doc.Code})
+		}
+	}
+	return sorted, nil
+}
+
+// WriteMarkdown writes a Markdown table with the given codes, their HTTP status
+// codes and descriptions to the given io.Writer. Codes are sorted
+// alphabetically.
+func WriteMarkdown(w io.Writer, docs []CodeDoc) error {
+	docs, err := sortedDocs(docs)
+	if err != nil {
+		return meh.Wrap(err, "sorted docs", nil)
+	}
+	var b strings.Builder
+	b.WriteString("| Code | HTTP status | Description |\n")
+	b.WriteString("|------|-------------|-------------|\n")
+	for _, doc := range docs {
+		status := "-"
+		if doc.HTTPStatus != 0 {
+			status = fmt.Sprintf("%d %s", doc.HTTPStatus, http.StatusText(doc.HTTPStatus))
+		}
+		_, _ = fmt.Fprintf(&b, "| `%s` | %s | %s |\n", doc.Code, status, markdownCell(doc.Description))
+	}
+	_, err = io.WriteString(w, b.String())
+	if err != nil {
+		return meh.NewInternalErrFromErr(err, "write", nil)
+	}
+	return nil
+}
+
+// markdownCell escapes the given text for usage in a Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Names of schemas in OpenAPIComponents.
+const (
+	// OpenAPISchemaErrorCode is the name of the string schema with an enum of
+	// all codes.
+	OpenAPISchemaErrorCode = "ErrorCode"
+	// OpenAPISchemaViolation is the name of the schema for meh.Violation.
+	OpenAPISchemaViolation = "Violation"
+	// OpenAPISchemaErrorResponse is the name of the schema for
+	// mehhttp.JSONResponse.
+	OpenAPISchemaErrorResponse = "ErrorResponse"
+)
+
+// OpenAPIResponseName returns the name of the response in OpenAPIComponents
+// for the given HTTP status code like "Error404".
+func OpenAPIResponseName(httpStatus int) string {
+	return fmt.Sprintf("Error%d", httpStatus)
+}
+
+// OpenAPIComponents returns the OpenAPI 3 components object for the given
+// codes. It holds the schemas for the problem body as responded by
+// mehhttp.JSONResponseRenderer and an enum of all codes. For each HTTP status
+// of the codes, a response is added that references the problem schema and
+// restricts codes to the ones with this status. Reference it in operations
+// using OpenAPIResponseName. The result can be marshalled to JSON or YAML.
+func OpenAPIComponents(docs []CodeDoc) (map[string]any, error) {
+	docs, err := sortedDocs(docs)
+	if err != nil {
+		return nil, meh.Wrap(err, "sorted docs", nil)
+	}
+	codes := make([]string, 0, len(docs))
+	codesByStatus := make(map[int][]string)
+	descriptions := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		codes = append(codes, string(doc.Code))
+		descriptions = append(descriptions, fmt.Sprintf("- `%s`: %s", doc.Code, doc.Description))
+		if doc.HTTPStatus != 0 {
+			codesByStatus[doc.HTTPStatus] = append(codesByStatus[doc.HTTPStatus], string(doc.Code))
+		}
+	}
+	schemaRef := func(name string) map[string]any {
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	responses := make(map[string]any, len(codesByStatus))
+	for status, statusCodes := range codesByStatus {
+		responses[OpenAPIResponseName(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": map[string]any{
+						"allOf": []any{
+							schemaRef(OpenAPISchemaErrorResponse),
+							map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"code": map[string]any{"type": "string", "enum": statusCodes},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	return map[string]any{
+		"schemas": map[string]any{
+			OpenAPISchemaErrorCode: map[string]any{
+				"type":        "string",
+				"description": "The error code.\n\n" + strings.Join(descriptions, "\n"),
+				"enum":        codes,
+			},
+			OpenAPISchemaViolation: map[string]any{
+				"type":     "object",
+				"required": []string{"constraint"},
+				"properties": map[string]any{
+					"path":       map[string]any{"type": "string", "description": "Path of the field like items[3].price."},
+					"constraint": map[string]any{"type": "string", "description": "Name of the violated constraint."},
+					"message":    map[string]any{"type": "string", "description": "Public message."},
+					"params": map[string]any{
+						"type":                 "object",
+						"additionalProperties": true,
+						"description":          "Parameters of the constraint.",
+					},
+				},
+			},
+			OpenAPISchemaErrorResponse: map[string]any{
+				"type":     "object",
+				"required": []string{"code"},
+				"properties": map[string]any{
+					"code":       schemaRef(OpenAPISchemaErrorCode),
+					"message_id": map[string]any{"type": "string", "description": "ID of the public message."},
+					"message":    map[string]any{"type": "string", "description": "Translated public message."},
+					"violations": map[string]any{"type": "array", "items": schemaRef(OpenAPISchemaViolation)},
+				},
+			},
+		},
+		"responses": responses,
+	}, nil
+}
+
+// WriteOpenAPIComponents writes the result of OpenAPIComponents as indented
+// JSON to the given io.Writer.
+func WriteOpenAPIComponents(w io.Writer, docs []CodeDoc) error {
+	components, err := OpenAPIComponents(docs)
+	if err != nil {
+		return meh.Wrap(err, "openapi components", nil)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(components)
+	if err != nil {
+		return meh.NewInternalErrFromErr(err, "encode", nil)
+	}
+	return nil
+}
+
+// WriteTypeScript writes a TypeScript union type with the given name of all
+// given codes to the given io.Writer. Descriptions are added as doc comment. If
+// no codes are given, the type is never.
+func WriteTypeScript(w io.Writer, docs []CodeDoc, typeName string) error {
+	docs, err := sortedDocs(docs)
+	if err != nil {
+		return meh.Wrap(err, "sorted docs", nil)
+	}
+	var b strings.Builder
+	b.WriteString("/**\n * Error codes.\n *\n")
+	for _, doc := range docs {
+		// Descriptions are joined into a single line and must not close the comment.
+		description := strings.Join(strings.Fields(doc.Description), " ")
+		_, _ = fmt.Fprintf(&b, " * - `%s`: %s\n", doc.Code, strings.ReplaceAll(description, "*/", "*\\/"))
+	}
+	b.WriteString(" */\n")
+	if len(docs) == 0 {
+		_, _ = fmt.Fprintf(&b, "export type %s = never;\n", typeName)
+	} else {
+		_, _ = fmt.Fprintf(&b, "export type %s =\n", typeName)
+	}
+	for i, doc := range docs {
+		code, _ := json.Marshal(string(doc.Code))
+		_, _ = fmt.Fprintf(&b, "  | %s", code)
+		if i == len(docs)-1 {
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	if err != nil {
+		return meh.NewInternalErrFromErr(err, "write", nil)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package mehdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+// docs are the CodeDoc used in tests.
+func docs() []CodeDoc {
+	return []CodeDoc{
+		{Code: meh.ErrNotFound, Description: "Not | found.", HTTPStatus: http.StatusNotFound},
+		{Code: "custom-gone", Description: "The resource\nis gone.", HTTPStatus: http.StatusNotFound},
+		{Code: meh.ErrInternal, Description: "Internal."},
+	}
+}
+
+// ApplyHTTPStatusCodeMappingSuite tests ApplyHTTPStatusCodeMapping.
+type ApplyHTTPStatusCodeMappingSuite struct {
+	suite.Suite
+}
+
+func (suite *ApplyHTTPStatusCodeMappingSuite) TestApply() {
+	original := docs()
+	applied := ApplyHTTPStatusCodeMapping(original, func(code meh.Code) int {
+		return http.StatusTeapot
+	})
+	suite.Equal(http.StatusNotFound, applied[0].HTTPStatus, "should keep set status")
+	suite.Equal(http.StatusTeapot, applied[2].HTTPStatus, "should apply mapping")
+	suite.Zero(original[2].HTTPStatus, "should not alter original")
+}
+
+func TestApplyHTTPStatusCodeMapping(t *testing.T) {
+	suite.Run(t, new(ApplyHTTPStatusCodeMappingSuite))
+}
+
+// WriteMarkdownSuite tests WriteMarkdown.
+type WriteMarkdownSuite struct {
+	suite.Suite
+}
+
+func (suite *WriteMarkdownSuite) TestOK() {
+	var b bytes.Buffer
+	suite.Require().NoError(WriteMarkdown(&b, docs()))
+	suite.Equal("| Code | HTTP status | Description |\n"+
+		"|------|-------------|-------------|\n"+
+		"| `custom-gone` | 404 Not Found | The resource is gone. |\n"+
+		"| `internal` | - | Internal. |\n"+
+		"| `not-found` | 404 Not Found | Not \\| found. |\n", b.String())
+}
+
+func (suite *WriteMarkdownSuite) TestDuplicate() {
+	var b bytes.Buffer
+	err := WriteMarkdown(&b, append(docs(), CodeDoc{Code: meh.ErrInternal}))
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should fail")
+	suite.Empty(b.String(), "should not write")
+}
+
+func (suite *WriteMarkdownSuite) TestEmptyCode() {
+	err := WriteMarkdown(&bytes.Buffer{}, []CodeDoc{{Description: "meh"}})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should fail")
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	suite.Run(t, new(WriteMarkdownSuite))
+}
+
+// OpenAPIComponentsSuite tests OpenAPIComponents and WriteOpenAPIComponents.
+type OpenAPIComponentsSuite struct {
+	suite.Suite
+	components map[string]any
+}
+
+func (suite *OpenAPIComponentsSuite) SetupTest() {
+	var b bytes.Buffer
+	suite.Require().NoError(WriteOpenAPIComponents(&b, docs()))
+	suite.Require().NoError(json.Unmarshal(b.Bytes(), &suite.components))
+}
+
+// get returns the value in the components at the given path.
+func (suite *OpenAPIComponentsSuite) get(path ...string) any {
+	var current any = suite.components
+	for _, key := range path {
+		m, ok := current.(map[string]any)
+		suite.Require().True(ok, "should be object at %q", key)
+		current = m[key]
+	}
+	return current
+}
+
+func (suite *OpenAPIComponentsSuite) TestCodeEnum() {
+	suite.Equal([]any{"custom-gone", "internal", "not-found"}, suite.get("schemas", OpenAPISchemaErrorCode, "enum"))
+}
+
+func (suite *OpenAPIComponentsSuite) TestResponseSchema() {
+	suite.Equal("#/components/schemas/"+OpenAPISchemaErrorCode,
+		suite.get("schemas", OpenAPISchemaErrorResponse, "properties", "code", "$ref"))
+	suite.NotNil(suite.get("schemas", OpenAPISchemaViolation), "should have violation schema")
+}
+
+func (suite *OpenAPIComponentsSuite) TestResponses() {
+	suite.Len(suite.get("responses"), 1, "should only have responses for set statuses")
+	allOf, ok := suite.get("responses", OpenAPIResponseName(http.StatusNotFound), "content", "application/json", "schema", "allOf").([]any)
+	suite.Require().True(ok, "should have all-of schema")
+	suite.Require().Len(allOf, 2)
+	suite.Equal([]any{"custom-gone", "not-found"}, allOf[1].(map[string]any)["properties"].(map[string]any)["code"].(map[string]any)["enum"])
+}
+
+func TestOpenAPIComponents(t *testing.T) {
+	suite.Run(t, new(OpenAPIComponentsSuite))
+}
+
+// WriteTypeScriptSuite tests WriteTypeScript.
+type WriteTypeScriptSuite struct {
+	suite.Suite
+}
+
+func (suite *WriteTypeScriptSuite) TestOK() {
+	var b bytes.Buffer
+	suite.Require().NoError(WriteTypeScript(&b, docs(), "ErrorCode"))
+	suite.Equal("/**\n * Error codes.\n *\n"+
+		" * - `custom-gone`: The resource is gone.\n"+
+		" * - `internal`: Internal.\n"+
+		" * - `not-found`: Not | found.\n"+
+		" */\n"+
+		"export type ErrorCode =\n"+
+		"  | \"custom-gone\"\n"+
+		"  | \"internal\"\n"+
+		"  | \"not-found\";\n", b.String())
+}
+
+func (suite *WriteTypeScriptSuite) TestEmpty() {
+	var b bytes.Buffer
+	suite.Require().NoError(WriteTypeScript(&b, nil, "ErrorCode"))
+	suite.Contains(b.String(), "export type ErrorCode = never;\n")
+}
+
+func TestWriteTypeScript(t *testing.T) {
+	suite.Run(t, new(WriteTypeScriptSuite))
+}
@@ -0,0 +1,85 @@
+// Package mehecho provides some wrappers and utils for bridging mehhttp and
+// echo.
+package mehecho
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/meh/mehlog"
+	"go.uber.org/zap"
+)
+
+// Keys for details added in LogAndRespondError.
+const (
+	// DetailKeyRoute holds the route template like "/users/:id" from
+	// echo.Context.Path.
+	DetailKeyRoute = "echo_route"
+	// DetailKeyHandler holds the name of the handler from echo.Context.Handler.
+	DetailKeyHandler = "echo_handler"
+	// DetailKeyStatus holds the status code of echo.HTTPError.
+	DetailKeyStatus = "echo_status"
+)
+
+// LogAndRespondError calls mehhttp.LogAndRespondError by using the
+// http.ResponseWriter and http.Request from the given echo.Context. The route
+// and handler name are added to details.
+func LogAndRespondError(logger *zap.Logger, c echo.Context, err error) {
+	mehhttp.LogAndRespondError(logger, c.Response(), c.Request(), meh.ApplyDetails(err, contextDetails(c)))
+}
+
+// contextDetails returns the details for the given echo.Context with keys
+// DetailKeyRoute and DetailKeyHandler. The handler name is taken from the
+// registered echo.Route as echo.Context.Handler returns a wrapping function.
+func contextDetails(c echo.Context) meh.Details {
+	details := meh.Details{
+		DetailKeyRoute: c.Path(),
+	}
+	if c.Echo() == nil {
+		return details
+	}
+	for _, route := range c.Echo().Routes() {
+		if route.Method == c.Request().Method && route.Path == c.Path() {
+			details[DetailKeyHandler] = route.Name
+			break
+		}
+	}
+	return details
+}
+
+// ErrorHandler returns an echo.HTTPErrorHandler that converts errors using
+// NewErr and responds them using LogAndRespondError. Set it via
+// echo.Echo.HTTPErrorHandler. If a response was already written, the error is
+// only logged.
+func ErrorHandler(logger *zap.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		err = NewErr(err)
+		if c.Response().Committed {
+			mehlog.Log(logger, meh.ApplyDetails(err, contextDetails(c)))
+			return
+		}
+		LogAndRespondError(logger, c, err)
+	}
+}
+
+// NewErr converts the given error if it is an echo.HTTPError, returned for
+// example for unknown routes or from binding. The meh.Code is determined from
+// the status code using mehhttp.CodeFromHTTPStatus. Other errors are returned
+// as they are.
+func NewErr(err error) error {
+	if _, ok := err.(*meh.Error); ok {
+		return err
+	}
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+	return &meh.Error{
+		Code:       mehhttp.CodeFromHTTPStatus(httpErr.Code),
+		WrappedErr: err,
+		Message:    fmt.Sprintf("%v", httpErr.Message),
+		Details:    meh.Details{DetailKeyStatus: httpErr.Code},
+	}
+}
@@ -0,0 +1,132 @@
+package mehecho
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/zaprec"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// handleUsers is a named handler for testing DetailKeyHandler.
+func handleUsers(_ echo.Context) error {
+	return meh.NewNotFoundErr("user not found", nil)
+}
+
+// ErrorHandlerSuite tests ErrorHandler.
+type ErrorHandlerSuite struct {
+	suite.Suite
+	logger *zap.Logger
+	rec    *zaprec.RecordStore
+	e      *echo.Echo
+}
+
+func (suite *ErrorHandlerSuite) SetupTest() {
+	suite.logger, suite.rec = zaprec.NewRecorder(nil)
+	suite.e = echo.New()
+	suite.e.HTTPErrorHandler = ErrorHandler(suite.logger)
+	mehhttp.SetHTTPStatusCodeMapping(func(code meh.Code) int {
+		switch code {
+		case meh.ErrNotFound:
+			return http.StatusNotFound
+		case meh.ErrBadInput:
+			return http.StatusBadRequest
+		default:
+			return http.StatusInternalServerError
+		}
+	})
+}
+
+func (suite *ErrorHandlerSuite) TearDownTest() {
+	mehhttp.SetHTTPStatusCodeMapping(func(_ meh.Code) int {
+		return http.StatusInternalServerError
+	})
+}
+
+// serve serves a GET-request with the given path.
+func (suite *ErrorHandlerSuite) serve(path string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	suite.e.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	return rr
+}
+
+// loggedDetail returns the string field with the given key of the first
+// logged record.
+func (suite *ErrorHandlerSuite) loggedDetail(key string) string {
+	records := suite.rec.Records()
+	suite.Require().NotEmpty(records, "should have been logged")
+	for _, field := range records[0].Fields {
+		if field.Key == key {
+			return field.String
+		}
+	}
+	return ""
+}
+
+func (suite *ErrorHandlerSuite) TestMehErr() {
+	suite.e.GET("/users/:id", handleUsers)
+	rr := suite.serve("/users/42")
+	suite.Equal(http.StatusNotFound, rr.Code, "should respond with mapped status code")
+	suite.Len(suite.rec.Records(), 1, "should have been logged")
+	suite.Equal("/users/:id", suite.loggedDetail("1/"+DetailKeyRoute), "should add route")
+	suite.Contains(suite.loggedDetail("1/"+DetailKeyHandler), "handleUsers", "should add handler name")
+}
+
+func (suite *ErrorHandlerSuite) TestHTTPError() {
+	suite.e.GET("/", func(_ echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "sad life")
+	})
+	rr := suite.serve("/")
+	suite.Equal(http.StatusBadRequest, rr.Code, "should respond with mapped status code")
+}
+
+func (suite *ErrorHandlerSuite) TestUnknownRoute() {
+	rr := suite.serve("/unknown")
+	suite.Equal(http.StatusNotFound, rr.Code, "should respond with mapped status code")
+	suite.Len(suite.rec.Records(), 1, "should have been logged")
+}
+
+func (suite *ErrorHandlerSuite) TestCommitted() {
+	suite.e.GET("/", func(c echo.Context) error {
+		_ = c.NoContent(http.StatusAccepted)
+		return errors.New("sad life")
+	})
+	rr := suite.serve("/")
+	suite.Equal(http.StatusAccepted, rr.Code, "should not respond again")
+	suite.Len(suite.rec.Records(), 1, "should have been logged")
+}
+
+func TestErrorHandler(t *testing.T) {
+	suite.Run(t, new(ErrorHandlerSuite))
+}
+
+// NewErrSuite tests NewErr.
+type NewErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewErrSuite) TestHTTPError() {
+	err := NewErr(echo.NewHTTPError(http.StatusConflict, "sad life")).(*meh.Error)
+	suite.Equal(meh.ErrConflict, err.Code, "should map status code")
+	suite.Equal("sad life", err.Message, "should use message")
+	suite.Equal(http.StatusConflict, err.Details[DetailKeyStatus], "should add status to details")
+}
+
+func (suite *NewErrSuite) TestMehErr() {
+	original := meh.NewBadInputErr("sad life", nil)
+	suite.Equal(original, NewErr(original), "should not alter meh errors")
+}
+
+func (suite *NewErrSuite) TestOther() {
+	original := errors.New("sad life")
+	suite.Equal(original, NewErr(original), "should not alter other errors")
+}
+
+func TestNewErr(t *testing.T) {
+	suite.Run(t, new(NewErrSuite))
+}
@@ -0,0 +1,100 @@
+// Package mehfiber provides some wrappers and utils for bridging mehhttp and
+// fiber.
+package mehfiber
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+)
+
+// Keys for details added in LogAndRespondError.
+const (
+	// DetailKeyRoute holds the route template like "/users/:id" from
+	// fiber.Route.
+	DetailKeyRoute = "fiber_route"
+	// DetailKeyHandler holds the name of the main handler from fiber.Route.
+	DetailKeyHandler = "fiber_handler"
+	// DetailKeyStatus holds the status code of fiber.Error.
+	DetailKeyStatus = "fiber_status"
+)
+
+// LogAndRespondError calls mehhttp.LogAndRenderError with request information
+// from the given fiber.Ctx and responds the result. The route and handler name
+// are added to details.
+func LogAndRespondError(logger *zap.Logger, c *fiber.Ctx, err error) error {
+	status, contentType, body := mehhttp.LogAndRenderError(logger, request(c), meh.ApplyDetails(err, contextDetails(c)))
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(status).Send(body)
+}
+
+// request creates an http.Request with the information from the given
+// fiber.Ctx that is needed in mehhttp.LogAndRenderError. All headers are
+// copied, so that response renderers can use them like Accept-Language for
+// translating public messages.
+func request(c *fiber.Ctx) *http.Request {
+	u, err := url.Parse(c.OriginalURL())
+	if err != nil {
+		u = &url.URL{Path: c.Path()}
+	}
+	r := &http.Request{
+		Method:     c.Method(),
+		URL:        u,
+		Host:       c.Hostname(),
+		RemoteAddr: c.Context().RemoteAddr().String(),
+		Header:     make(http.Header),
+	}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		r.Header.Add(string(key), string(value))
+	})
+	return r.WithContext(c.UserContext())
+}
+
+// contextDetails returns the details for the given fiber.Ctx with keys
+// DetailKeyRoute and DetailKeyHandler.
+func contextDetails(c *fiber.Ctx) meh.Details {
+	route := c.Route()
+	details := meh.Details{
+		DetailKeyRoute: route.Path,
+	}
+	if len(route.Handlers) > 0 {
+		handler := route.Handlers[len(route.Handlers)-1]
+		details[DetailKeyHandler] = runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	}
+	return details
+}
+
+// ErrorHandler returns a fiber.ErrorHandler that converts errors using NewErr
+// and responds them using LogAndRespondError. Set it via
+// fiber.Config.ErrorHandler.
+func ErrorHandler(logger *zap.Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		return LogAndRespondError(logger, c, NewErr(err))
+	}
+}
+
+// NewErr converts the given error if it is a fiber.Error, returned for example
+// for unknown routes or from body parsing. The meh.Code is determined from the
+// status code using mehhttp.CodeFromHTTPStatus. Other errors are returned as
+// they are.
+func NewErr(err error) error {
+	if _, ok := err.(*meh.Error); ok {
+		return err
+	}
+	var fiberErr *fiber.Error
+	if !errors.As(err, &fiberErr) {
+		return err
+	}
+	return &meh.Error{
+		Code:       mehhttp.CodeFromHTTPStatus(fiberErr.Code),
+		WrappedErr: err,
+		Message:    fiberErr.Message,
+		Details:    meh.Details{DetailKeyStatus: fiberErr.Code},
+	}
+}
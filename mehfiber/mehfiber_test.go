@@ -0,0 +1,156 @@
+package mehfiber
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/zaprec"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// handleUsers is a named handler for testing DetailKeyHandler.
+func handleUsers(_ *fiber.Ctx) error {
+	return meh.NewNotFoundErr("user not found", nil)
+}
+
+// ErrorHandlerSuite tests ErrorHandler.
+type ErrorHandlerSuite struct {
+	suite.Suite
+	logger *zap.Logger
+	rec    *zaprec.RecordStore
+	app    *fiber.App
+}
+
+func (suite *ErrorHandlerSuite) SetupTest() {
+	suite.logger, suite.rec = zaprec.NewRecorder(nil)
+	suite.app = fiber.New(fiber.Config{
+		ErrorHandler: ErrorHandler(suite.logger),
+	})
+	mehhttp.SetHTTPStatusCodeMapping(func(code meh.Code) int {
+		switch code {
+		case meh.ErrNotFound:
+			return http.StatusNotFound
+		case meh.ErrBadInput:
+			return http.StatusBadRequest
+		default:
+			return http.StatusInternalServerError
+		}
+	})
+}
+
+func (suite *ErrorHandlerSuite) TearDownTest() {
+	mehhttp.SetHTTPStatusCodeMapping(func(_ meh.Code) int {
+		return http.StatusInternalServerError
+	})
+	mehhttp.SetResponseRenderer(mehhttp.EmptyResponseRenderer)
+}
+
+// serve serves a GET-request with the given path.
+func (suite *ErrorHandlerSuite) serve(path string) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", "007")
+	res, err := suite.app.Test(req)
+	suite.Require().NoError(err, "request should not fail")
+	return res
+}
+
+// loggedDetail returns the string field with the given key of the first
+// logged record.
+func (suite *ErrorHandlerSuite) loggedDetail(key string) string {
+	records := suite.rec.Records()
+	suite.Require().NotEmpty(records, "should have been logged")
+	for _, field := range records[0].Fields {
+		if field.Key == key {
+			return field.String
+		}
+	}
+	return ""
+}
+
+func (suite *ErrorHandlerSuite) TestMehErr() {
+	suite.app.Get("/users/:id", handleUsers)
+	res := suite.serve("/users/42?hello=world")
+	suite.Equal(http.StatusNotFound, res.StatusCode, "should respond with mapped status code")
+	suite.Len(suite.rec.Records(), 1, "should have been logged")
+	suite.Equal("/users/:id", suite.loggedDetail("1/"+DetailKeyRoute), "should add route")
+	suite.Contains(suite.loggedDetail("1/"+DetailKeyHandler), "handleUsers", "should add handler name")
+	suite.Equal("/users/42?hello=world", suite.loggedDetail("0/http_req_url"), "should add request url")
+	suite.Equal("007", suite.loggedDetail("0/http_req_user_agent"), "should add user agent")
+}
+
+func (suite *ErrorHandlerSuite) TestFiberError() {
+	suite.app.Get("/", func(_ *fiber.Ctx) error {
+		return fiber.NewError(http.StatusBadRequest, "sad life")
+	})
+	res := suite.serve("/")
+	suite.Equal(http.StatusBadRequest, res.StatusCode, "should respond with mapped status code")
+}
+
+func (suite *ErrorHandlerSuite) TestUnknownRoute() {
+	res := suite.serve("/unknown")
+	suite.Equal(http.StatusNotFound, res.StatusCode, "should respond with mapped status code")
+	suite.Len(suite.rec.Records(), 1, "should have been logged")
+}
+
+func (suite *ErrorHandlerSuite) TestRenderer() {
+	mehhttp.SetResponseRenderer(mehhttp.JSONResponseRenderer)
+	suite.app.Get("/", handleUsers)
+	res := suite.serve("/")
+	suite.Equal("application/json", res.Header.Get(fiber.HeaderContentType), "should use renderer")
+}
+
+func (suite *ErrorHandlerSuite) TestLocalizedRenderer() {
+	c := meh.NewCatalog(language.English)
+	suite.Require().NoError(c.Add(language.English, "user-not-found", "User not found."))
+	suite.Require().NoError(c.Add(language.German, "user-not-found", "Benutzer nicht gefunden."))
+	mehhttp.SetCatalog(c)
+	defer mehhttp.SetCatalog(nil)
+	mehhttp.SetResponseRenderer(mehhttp.JSONResponseRenderer)
+	suite.app.Get("/", func(_ *fiber.Ctx) error {
+		return meh.WithPublicMessage(meh.NewNotFoundErr("user not found", nil), "user-not-found", nil)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	res, err := suite.app.Test(req)
+	suite.Require().NoError(err, "request should not fail")
+	var response mehhttp.JSONResponse
+	suite.Require().NoError(json.NewDecoder(res.Body).Decode(&response), "should respond json")
+	suite.Equal("Benutzer nicht gefunden.", response.Message, "should translate using accept-language")
+}
+
+func TestErrorHandler(t *testing.T) {
+	suite.Run(t, new(ErrorHandlerSuite))
+}
+
+// NewErrSuite tests NewErr.
+type NewErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewErrSuite) TestFiberError() {
+	err := NewErr(fiber.NewError(http.StatusConflict, "sad life")).(*meh.Error)
+	suite.Equal(meh.ErrConflict, err.Code, "should map status code")
+	suite.Equal("sad life", err.Message, "should use message")
+	suite.Equal(http.StatusConflict, err.Details[DetailKeyStatus], "should add status to details")
+}
+
+func (suite *NewErrSuite) TestMehErr() {
+	original := meh.NewBadInputErr("sad life", nil)
+	suite.Equal(original, NewErr(original), "should not alter meh errors")
+}
+
+func (suite *NewErrSuite) TestOther() {
+	original := errors.New("sad life")
+	suite.Equal(original, NewErr(original), "should not alter other errors")
+}
+
+func TestNewErr(t *testing.T) {
+	suite.Run(t, new(NewErrSuite))
+}
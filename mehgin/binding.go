@@ -0,0 +1,146 @@
+package mehgin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/lefinal/meh"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Constraints for meh.Violation that are used for errors not originating from
+// validation.
+const (
+	// ConstraintType is used for values with wrong JSON type.
+	ConstraintType = "type"
+	// ConstraintSyntax is used for malformed JSON.
+	ConstraintSyntax = "syntax"
+	// ConstraintRequired is used for an empty body. It is the same as the tag
+	// from validator.
+	ConstraintRequired = "required"
+)
+
+// Keys for meh.Violation.Params set in NewBindingErr.
+const (
+	// ViolationParamKeyParam holds the parameter of the validation tag like "10"
+	// for "max=10", the expected type for ConstraintType or the offset for
+	// ConstraintSyntax.
+	ViolationParamKeyParam = "param"
+	// ViolationParamKeyType holds the type of the offending value.
+	ViolationParamKeyType = "type"
+)
+
+// UseJSONFieldNames registers a tag name function for the validator of gin's
+// default binding.Validator in order to use names from json struct tags in
+// field paths of validation errors. Otherwise, Go struct field names are used.
+func UseJSONFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		default:
+			return name
+		}
+	})
+}
+
+// ShouldBindJSON calls gin.Context.ShouldBindJSON and converts errors using
+// NewBindingErr.
+func ShouldBindJSON(c *gin.Context, obj any) error {
+	err := c.ShouldBindJSON(obj)
+	if err != nil {
+		return NewBindingErr(err)
+	}
+	return nil
+}
+
+// NewBindingErr creates a meh.ErrBadInput for the given error returned from
+// binding in gin, like with gin.Context.ShouldBindJSON. Validation errors,
+// JSON syntax and type errors are converted to a list of meh.Violation in
+// details with key meh.DetailKeyViolations. Paths are relative to the bound
+// object. Use UseJSONFieldNames in order to get JSON paths for validation
+// errors.
+func NewBindingErr(err error) error {
+	return meh.NewBadInputErrFromErr(err, "bind", meh.Details{
+		meh.DetailKeyViolations: bindingViolations(err),
+	})
+}
+
+// bindingViolations returns the meh.Violation list for the given binding
+// error.
+func bindingViolations(err error) []meh.Violation {
+	violations := make([]meh.Violation, 0)
+	var validationErrs validator.ValidationErrors
+	var sliceValidationErr binding.SliceValidationError
+	var unmarshalTypeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fieldErr := range validationErrs {
+			violations = append(violations, newViolation(fieldPath(fieldErr.Namespace()), fieldErr.Tag(),
+				fmt.Sprintf("failed on the '%s' constraint", fieldErr.Tag()), fieldErr.Param(), fieldErr.Type().String()))
+		}
+	case errors.As(err, &sliceValidationErr):
+		// Gin does not provide the original indices of failed elements, so
+		// violations are flattened.
+		for _, elementErr := range sliceValidationErr {
+			violations = append(violations, bindingViolations(elementErr)...)
+		}
+	case errors.As(err, &unmarshalTypeErr):
+		typeName := unmarshalTypeErr.Type.String()
+		violations = append(violations, newViolation(unmarshalTypeErr.Field, ConstraintType,
+			fmt.Sprintf("must be of type %s", typeName), typeName, unmarshalTypeErr.Value))
+	case errors.As(err, &syntaxErr):
+		violations = append(violations, newViolation("", ConstraintSyntax, "malformed json",
+			strconv.FormatInt(syntaxErr.Offset, 10), ""))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		violations = append(violations, newViolation("", ConstraintSyntax, "malformed json", "", ""))
+	case errors.Is(err, io.EOF):
+		violations = append(violations, newViolation("", ConstraintRequired, "body required", "", ""))
+	}
+	return violations
+}
+
+// newViolation creates a meh.Violation with the given parameter and type in
+// meh.Violation.Params if set.
+func newViolation(path string, constraint string, message string, param string, valueType string) meh.Violation {
+	var params map[string]any
+	if param != "" || valueType != "" {
+		params = make(map[string]any)
+	}
+	if param != "" {
+		params[ViolationParamKeyParam] = param
+	}
+	if valueType != "" {
+		params[ViolationParamKeyType] = valueType
+	}
+	return meh.Violation{
+		Path:       path,
+		Constraint: constraint,
+		Message:    message,
+		Params:     params,
+	}
+}
+
+// fieldPath returns the path relative to the bound object for the given
+// namespace from validator.FieldError by removing the root struct name.
+func fieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return path
+}
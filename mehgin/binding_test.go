@@ -0,0 +1,97 @@
+package mehgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bindingAddress is used as nested object in bindingUser.
+type bindingAddress struct {
+	Street string `json:"street" binding:"required"`
+}
+
+// bindingUser is used for testing binding errors.
+type bindingUser struct {
+	Name    string         `json:"name" binding:"required,max=5"`
+	Age     int            `json:"age" binding:"min=18"`
+	Address bindingAddress `json:"address"`
+}
+
+// ShouldBindJSONSuite tests ShouldBindJSON and NewBindingErr.
+type ShouldBindJSONSuite struct {
+	suite.Suite
+}
+
+func (suite *ShouldBindJSONSuite) SetupSuite() {
+	UseJSONFieldNames()
+}
+
+// bind binds the given body and returns the error.
+func (suite *ShouldBindJSONSuite) bind(body string, obj any) error {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return ShouldBindJSON(c, obj)
+}
+
+func (suite *ShouldBindJSONSuite) TestOK() {
+	var user bindingUser
+	err := suite.bind(`{"name":"meh","age":18,"address":{"street":"Main"}}`, &user)
+	suite.NoError(err, "should not fail")
+	suite.Equal("meh", user.Name)
+}
+
+func (suite *ShouldBindJSONSuite) TestValidation() {
+	err := suite.bind(`{"name":"too long","age":3}`, &bindingUser{})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should return bad input")
+	suite.Equal([]meh.Violation{
+		{Path: "name", Constraint: "max", Message: "failed on the 'max' constraint", Params: map[string]any{"param": "5", "type": "string"}},
+		{Path: "age", Constraint: "min", Message: "failed on the 'min' constraint", Params: map[string]any{"param": "18", "type": "int"}},
+		{Path: "address.street", Constraint: "required", Message: "failed on the 'required' constraint", Params: map[string]any{"type": "string"}},
+	}, meh.Violations(err))
+}
+
+func (suite *ShouldBindJSONSuite) TestSlice() {
+	err := suite.bind(`[{"street":"Main"},{},{}]`, &[]bindingAddress{})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should return bad input")
+	suite.Len(meh.Violations(err), 2, "should have violations for each element")
+}
+
+func (suite *ShouldBindJSONSuite) TestType() {
+	err := suite.bind(`{"name":"meh","age":"old","address":{"street":"Main"}}`, &bindingUser{})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should return bad input")
+	suite.Equal([]meh.Violation{
+		{Path: "age", Constraint: ConstraintType, Message: "must be of type int", Params: map[string]any{"param": "int", "type": "string"}},
+	}, meh.Violations(err))
+}
+
+func (suite *ShouldBindJSONSuite) TestSyntax() {
+	err := suite.bind(`{"name":`, &bindingUser{})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should return bad input")
+	suite.Equal([]meh.Violation{
+		{Constraint: ConstraintSyntax, Message: "malformed json"},
+	}, meh.Violations(err))
+}
+
+func (suite *ShouldBindJSONSuite) TestMalformed() {
+	err := suite.bind(`{"name" "meh"}`, &bindingUser{})
+	suite.Equal([]meh.Violation{
+		{Constraint: ConstraintSyntax, Message: "malformed json", Params: map[string]any{"param": "9"}},
+	}, meh.Violations(err))
+}
+
+func (suite *ShouldBindJSONSuite) TestEmptyBody() {
+	err := suite.bind(``, &bindingUser{})
+	suite.Equal([]meh.Violation{
+		{Constraint: ConstraintRequired, Message: "body required"},
+	}, meh.Violations(err))
+}
+
+func TestShouldBindJSON(t *testing.T) {
+	suite.Run(t, new(ShouldBindJSONSuite))
+}
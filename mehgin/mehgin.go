@@ -2,13 +2,118 @@
 package mehgin
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/lefinal/meh"
 	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/meh/mehlog"
 	"go.uber.org/zap"
+	"net/http"
+	"reflect"
+	"runtime"
+)
+
+// Keys for details added in LogAndRespondError.
+const (
+	// DetailKeyRoute holds the route template like "/users/:id" from
+	// gin.Context.FullPath.
+	DetailKeyRoute = "gin_route"
+	// DetailKeyHandler holds the name of the main handler from
+	// gin.Context.HandlerName.
+	DetailKeyHandler = "gin_handler"
+	// DetailKeyPanic holds the recovered value in Recovery.
+	DetailKeyPanic = "panic"
 )
 
 // LogAndRespondError calls mehhttp.LogAndRespondError by using the
-// http.ResponseWriter and http.Request from the given gin.Context.
+// http.ResponseWriter and http.Request from the given gin.Context. The route
+// and handler name are added to details.
 func LogAndRespondError(logger *zap.Logger, c *gin.Context, err error) {
-	mehhttp.LogAndRespondError(logger, c.Writer, c.Request, err)
+	mehhttp.LogAndRespondError(logger, c.Writer, c.Request, meh.ApplyDetails(err, contextDetails(c)))
+}
+
+// contextKeyHandlerName is the key in gin.Context for the name of the handler
+// function passed to Handler. Otherwise, the name of the wrapping function
+// would be used as handler name.
+const contextKeyHandlerName = "mehgin-handler-name"
+
+// contextDetails returns the details for the given gin.Context with keys
+// DetailKeyRoute and DetailKeyHandler.
+func contextDetails(c *gin.Context) meh.Details {
+	handlerName := c.HandlerName()
+	if name := c.GetString(contextKeyHandlerName); name != "" {
+		handlerName = name
+	}
+	return meh.Details{
+		DetailKeyRoute:   c.FullPath(),
+		DetailKeyHandler: handlerName,
+	}
+}
+
+// Middleware returns a gin.HandlerFunc that handles errors added via
+// gin.Context.Error after the following handlers ran. The last error is
+// responded using LogAndRespondError while all previous ones are only logged.
+// If a response was already written, all errors are only logged.
+func Middleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 {
+			return
+		}
+		errs := c.Errors
+		if !c.Writer.Written() {
+			last := errs[len(errs)-1]
+			errs = errs[:len(errs)-1]
+			defer LogAndRespondError(logger, c, last.Err)
+		}
+		for _, err := range errs {
+			mehlog.Log(logger, meh.ApplyDetails(err.Err, contextDetails(c)))
+		}
+	}
+}
+
+// Recovery returns a gin.HandlerFunc that recovers from panics in the following
+// handlers and responds with meh.ErrInternal using LogAndRespondError.
+// Recovered errors are wrapped while other values are added to details with
+// key DetailKeyPanic. The stack trace is applied in both cases. Panics with
+// http.ErrAbortHandler are passed on.
+func Recovery(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			var err error
+			if rErr, ok := r.(error); ok {
+				if errors.Is(rErr, http.ErrAbortHandler) {
+					panic(r)
+				}
+				err = meh.NewInternalErrFromErr(rErr, "panic", nil)
+			} else {
+				err = meh.NewInternalErr("panic", meh.Details{DetailKeyPanic: fmt.Sprintf("%+v", r)})
+			}
+			c.Abort()
+			LogAndRespondError(logger, c, meh.ApplyStackTrace(err))
+		}()
+		c.Next()
+	}
+}
+
+// Handler adapts the given handler function that returns an error to a
+// gin.HandlerFunc. Errors are added to the gin.Context via gin.Context.Error
+// and the following handlers are aborted. Use it along with Middleware in order
+// to respond the error.
+func Handler(handler func(c *gin.Context) error) gin.HandlerFunc {
+	handlerName := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	return func(c *gin.Context) {
+		c.Set(contextKeyHandlerName, handlerName)
+		err := handler(c)
+		if err == nil {
+			return
+		}
+		_ = c.Error(err)
+		c.Abort()
+	}
 }
@@ -0,0 +1,157 @@
+package mehgin
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/zaprec"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// handleUsers is a named handler for testing DetailKeyHandler.
+func handleUsers(_ *gin.Context) error {
+	return meh.NewNotFoundErr("user not found", nil)
+}
+
+// MiddlewareSuite tests Middleware, Recovery and Handler.
+type MiddlewareSuite struct {
+	suite.Suite
+	logger *zap.Logger
+	rec    *zaprec.RecordStore
+	engine *gin.Engine
+}
+
+func (suite *MiddlewareSuite) SetupTest() {
+	suite.logger, suite.rec = zaprec.NewRecorder(nil)
+	suite.engine = gin.New()
+	suite.engine.Use(Middleware(suite.logger), Recovery(suite.logger))
+	mehhttp.SetHTTPStatusCodeMapping(func(code meh.Code) int {
+		switch code {
+		case meh.ErrNotFound:
+			return http.StatusNotFound
+		case meh.ErrBadInput:
+			return http.StatusBadRequest
+		default:
+			return http.StatusInternalServerError
+		}
+	})
+}
+
+func (suite *MiddlewareSuite) TearDownTest() {
+	mehhttp.SetHTTPStatusCodeMapping(func(_ meh.Code) int {
+		return http.StatusInternalServerError
+	})
+}
+
+// serve serves a GET-request with the given path.
+func (suite *MiddlewareSuite) serve(path string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	suite.engine.ServeHTTP(rr, req)
+	return rr
+}
+
+// loggedDetail returns the field with the given key of the logged record with
+// the given index.
+func (suite *MiddlewareSuite) loggedDetail(record int, key string) any {
+	records := suite.rec.Records()
+	suite.Require().Greater(len(records), record, "should have been logged")
+	for _, field := range records[record].Fields {
+		if field.Key == key {
+			return field.String
+		}
+	}
+	return nil
+}
+
+func (suite *MiddlewareSuite) TestHandler() {
+	suite.engine.GET("/users/:id", Handler(handleUsers))
+	rr := suite.serve("/users/42")
+	suite.Equal(http.StatusNotFound, rr.Code, "should respond with mapped status code")
+	suite.Len(suite.rec.Records(), 1, "should have been logged")
+	suite.Equal("/users/:id", suite.loggedDetail(0, "1/"+DetailKeyRoute), "should add route")
+	suite.Contains(suite.loggedDetail(0, "1/"+DetailKeyHandler), "handleUsers", "should add handler name")
+}
+
+func (suite *MiddlewareSuite) TestHandlerAbort() {
+	called := false
+	suite.engine.GET("/", Handler(handleUsers), func(_ *gin.Context) {
+		called = true
+	})
+	suite.serve("/")
+	suite.False(called, "should abort following handlers")
+}
+
+func (suite *MiddlewareSuite) TestHandlerOK() {
+	suite.engine.GET("/", Handler(func(c *gin.Context) error {
+		c.Status(http.StatusNoContent)
+		return nil
+	}))
+	rr := suite.serve("/")
+	suite.Equal(http.StatusNoContent, rr.Code, "should respond from handler")
+	suite.Empty(suite.rec.Records(), "should not log")
+}
+
+func (suite *MiddlewareSuite) TestMultipleErrors() {
+	suite.engine.GET("/", func(c *gin.Context) {
+		_ = c.Error(meh.NewInternalErr("first", nil))
+		_ = c.Error(meh.NewBadInputErr("second", nil))
+	})
+	rr := suite.serve("/")
+	suite.Equal(http.StatusBadRequest, rr.Code, "should respond last error")
+	suite.Len(suite.rec.Records(), 2, "should log all errors")
+}
+
+func (suite *MiddlewareSuite) TestAlreadyWritten() {
+	suite.engine.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+		c.Writer.WriteHeaderNow()
+		_ = c.Error(meh.NewBadInputErr("sad life", nil))
+	})
+	rr := suite.serve("/")
+	suite.Equal(http.StatusAccepted, rr.Code, "should not respond again")
+	suite.Len(suite.rec.Records(), 1, "should log error")
+}
+
+func (suite *MiddlewareSuite) TestRecovery() {
+	suite.engine.GET("/", func(_ *gin.Context) {
+		panic("sad life")
+	})
+	rr := suite.serve("/")
+	suite.Equal(http.StatusInternalServerError, rr.Code, "should respond internal error")
+	suite.Require().Len(suite.rec.Records(), 1, "should have been logged")
+	suite.Equal("sad life", suite.loggedDetail(0, "2/"+DetailKeyPanic), "should add panic value")
+}
+
+func (suite *MiddlewareSuite) TestRecoveryErr() {
+	panicErr := errors.New("sad life")
+	suite.engine.GET("/", func(_ *gin.Context) {
+		panic(panicErr)
+	})
+	rr := suite.serve("/")
+	suite.Equal(http.StatusInternalServerError, rr.Code, "should respond internal error")
+	suite.Require().Len(suite.rec.Records(), 1, "should have been logged")
+	suite.Contains(suite.rec.Records()[0].Entry.Message, "sad life", "should wrap error")
+}
+
+func (suite *MiddlewareSuite) TestRecoveryAbortHandler() {
+	suite.engine.GET("/", func(_ *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+	suite.Panics(func() {
+		suite.serve("/")
+	}, "should pass on abort panics")
+}
+
+func TestMiddleware(t *testing.T) {
+	suite.Run(t, new(MiddlewareSuite))
+}
@@ -38,6 +38,51 @@ func HTTPStatusCode(e error) int {
 	return httpStatusCodeMapper(meh.ErrorCode(e))
 }
 
+// RespondHook is called for each error that is responded via
+// LogAndRespondError with the request and the responded HTTP status code.
+type RespondHook func(r *http.Request, err error, status int)
+
+var (
+	// respondHooks are the registered hooks that are called in
+	// LogAndRespondError.
+	respondHooks = make(map[int]RespondHook)
+	// nextRespondHookID is the id for the next hook that is added to
+	// respondHooks.
+	nextRespondHookID = 0
+	// respondHooksMutex locks respondHooks and nextRespondHookID.
+	respondHooksMutex sync.RWMutex
+)
+
+// AddRespondHook registers the given RespondHook to be called for each
+// responded error. The returned function removes the hook again.
+func AddRespondHook(hook RespondHook) func() {
+	respondHooksMutex.Lock()
+	defer respondHooksMutex.Unlock()
+	id := nextRespondHookID
+	nextRespondHookID++
+	respondHooks[id] = hook
+	return func() {
+		respondHooksMutex.Lock()
+		defer respondHooksMutex.Unlock()
+		delete(respondHooks, id)
+	}
+}
+
+// callRespondHooks calls all registered hooks with the given request, error and
+// status. Hooks are called without holding the lock, so that they may add or
+// remove hooks.
+func callRespondHooks(r *http.Request, err error, status int) {
+	respondHooksMutex.RLock()
+	hooksToCall := make([]RespondHook, 0, len(respondHooks))
+	for _, hook := range respondHooks {
+		hooksToCall = append(hooksToCall, hook)
+	}
+	respondHooksMutex.RUnlock()
+	for _, hook := range hooksToCall {
+		hook(r, err, status)
+	}
+}
+
 const (
 	// ErrCommunication is used for all problems regarding client communication. As
 	// communication is unstable by nature, this should not be reported as classic
@@ -49,20 +94,12 @@ const (
 )
 
 // LogAndRespondError logs the given meh.Error and responds using the status
-// code mapping set via SetHTTPStatusCodeMapping. The responded message will
-// always be empty.
+// code mapping set via SetHTTPStatusCodeMapping. The response body is created
+// using the ResponseRenderer set via SetResponseRenderer, which responds an
+// empty message per default.
 func LogAndRespondError(logger *zap.Logger, w http.ResponseWriter, r *http.Request, e error) {
-	// Add request details.
-	e = meh.ApplyDetails(e, meh.Details{
-		"http_req_url":         r.URL.String(),
-		"http_req_host":        r.Host,
-		"http_req_method":      r.Method,
-		"http_req_user_agent":  r.UserAgent(),
-		"http_req_remote_addr": r.RemoteAddr,
-	})
-	mehlog.Log(logger, e)
-	httpStatus := HTTPStatusCode(e)
-	err := respondHTTP(w, "", httpStatus)
+	httpStatus, contentType, body := LogAndRenderError(logger, r, e)
+	err := respondHTTP(w, contentType, body, httpStatus)
 	if err != nil {
 		mehlog.Log(logger, meh.Wrap(err, "respond http", meh.Details{
 			"status": httpStatus,
@@ -71,12 +108,58 @@ func LogAndRespondError(logger *zap.Logger, w http.ResponseWriter, r *http.Reque
 	}
 }
 
-// respondHTTP responds the given message with the status to the
+// LogAndRenderError is the same as LogAndRespondError but returns the HTTP
+// status code, content type and body instead of writing them. This is used
+// for frameworks that do not use http.ResponseWriter.
+func LogAndRenderError(logger *zap.Logger, r *http.Request, e error) (int, string, []byte) {
+	e = meh.ApplyDetails(e, RequestDetails(r))
+	mehlog.Log(logger, e)
+	httpStatus := HTTPStatusCode(e)
+	callRespondHooks(r, e, httpStatus)
+	contentType, body := RenderResponse(r, e, httpStatus)
+	return httpStatus, contentType, body
+}
+
+// RequestDetails returns the details for the given http.Request that are added
+// in LogAndRespondError.
+func RequestDetails(r *http.Request) meh.Details {
+	return meh.Details{
+		"http_req_url":         r.URL.String(),
+		"http_req_host":        r.Host,
+		"http_req_method":      r.Method,
+		"http_req_user_agent":  r.UserAgent(),
+		"http_req_remote_addr": r.RemoteAddr,
+	}
+}
+
+// CodeFromHTTPStatus returns the meh.Code for the given HTTP status code. This
+// is used for framework-native errors holding status codes. Unknown client
+// errors result in meh.ErrBadInput and all others in meh.ErrInternal.
+func CodeFromHTTPStatus(status int) meh.Code {
+	switch status {
+	case http.StatusUnauthorized:
+		return meh.ErrUnauthorized
+	case http.StatusForbidden:
+		return meh.ErrForbidden
+	case http.StatusNotFound:
+		return meh.ErrNotFound
+	case http.StatusConflict:
+		return meh.ErrConflict
+	case http.StatusServiceUnavailable:
+		return meh.ErrUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return meh.ErrBadInput
+	}
+	return meh.ErrInternal
+}
+
+// respondHTTP responds the given body with the content type and status to the
 // http.ResponseWriter.
-func respondHTTP(w http.ResponseWriter, message string, status int) error {
-	w.Header().Set("Content-Type", "text/plain")
+func respondHTTP(w http.ResponseWriter, contentType string, body []byte, status int) error {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
-	_, err := w.Write([]byte(message))
+	_, err := w.Write(body)
 	if err != nil {
 		return &meh.Error{
 			Code:       ErrCommunication,
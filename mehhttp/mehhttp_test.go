@@ -147,3 +147,75 @@ func TestSetHTTPStatusCodeMapping(t *testing.T) {
 	LogAndRespondError(zap.NewNop(), rr, req, &meh.Error{Code: meh.ErrInternal})
 	assert.Equal(t, http.StatusTeapot, rr.Code, "should return correct code")
 }
+
+// TestAddRespondHook tests AddRespondHook.
+func TestAddRespondHook(t *testing.T) {
+	var calledReq *http.Request
+	var calledErr error
+	calledStatus := 0
+	calls := 0
+	removeHook := AddRespondHook(func(r *http.Request, err error, status int) {
+		calls++
+		calledReq = r
+		calledErr = err
+		calledStatus = status
+	})
+	req, err := http.NewRequest(http.MethodGet, "http://meow", nil)
+	require.Nil(t, err, "create request should not fail")
+	e := meh.NewNotFoundErr("sad life", nil)
+	LogAndRespondError(zap.NewNop(), httptest.NewRecorder(), req, e)
+	assert.Equal(t, 1, calls, "should have called hook")
+	assert.Equal(t, req, calledReq, "should pass request")
+	assert.Equal(t, meh.ErrNotFound, meh.ErrorCode(calledErr), "should pass error")
+	assert.Equal(t, HTTPStatusCode(e), calledStatus, "should pass status")
+	removeHook()
+	LogAndRespondError(zap.NewNop(), httptest.NewRecorder(), req, e)
+	assert.Equal(t, 1, calls, "should not call removed hook")
+}
+
+// TestRespondHookRemovesItself assures that respond hooks can remove themselves
+// without deadlocking.
+func TestRespondHookRemovesItself(t *testing.T) {
+	calls := 0
+	var removeHook func()
+	removeHook = AddRespondHook(func(_ *http.Request, _ error, _ int) {
+		calls++
+		removeHook()
+	})
+	req, err := http.NewRequest(http.MethodGet, "http://meow", nil)
+	require.Nil(t, err, "create request should not fail")
+	LogAndRespondError(zap.NewNop(), httptest.NewRecorder(), req, meh.NewNotFoundErr("sad life", nil))
+	LogAndRespondError(zap.NewNop(), httptest.NewRecorder(), req, meh.NewNotFoundErr("sad life", nil))
+	assert.Equal(t, 1, calls, "should have called hook once")
+}
+
+// TestLogAndRenderError tests LogAndRenderError.
+func TestLogAndRenderError(t *testing.T) {
+	logger, rec := zaprec.NewRecorder(nil)
+	req, err := http.NewRequest(http.MethodGet, "http://meow", nil)
+	require.Nil(t, err, "create request should not fail")
+	e := meh.NewInternalErr("sad life", nil)
+	status, contentType, body := LogAndRenderError(logger, req, e)
+	assert.Equal(t, HTTPStatusCode(e), status, "should return status")
+	assert.Equal(t, "text/plain", contentType, "should return content type")
+	assert.Empty(t, body, "should return empty body")
+	assert.Len(t, rec.Records(), 1, "should have been logged")
+}
+
+// TestCodeFromHTTPStatus tests CodeFromHTTPStatus.
+func TestCodeFromHTTPStatus(t *testing.T) {
+	tests := map[int]meh.Code{
+		http.StatusBadRequest:          meh.ErrBadInput,
+		http.StatusUnauthorized:        meh.ErrUnauthorized,
+		http.StatusForbidden:           meh.ErrForbidden,
+		http.StatusNotFound:            meh.ErrNotFound,
+		http.StatusMethodNotAllowed:    meh.ErrBadInput,
+		http.StatusConflict:            meh.ErrConflict,
+		http.StatusServiceUnavailable:  meh.ErrUnavailable,
+		http.StatusInternalServerError: meh.ErrInternal,
+		http.StatusBadGateway:          meh.ErrInternal,
+	}
+	for status, code := range tests {
+		assert.Equal(t, code, CodeFromHTTPStatus(status), "should map status %d", status)
+	}
+}
@@ -0,0 +1,105 @@
+package mehhttp
+
+import (
+	"encoding/json"
+	"github.com/lefinal/meh"
+	"net/http"
+	"sync"
+)
+
+// ResponseRenderer renders the response body for the given error that is
+// responded with the given HTTP status code for the given http.Request. It
+// returns the content type and body.
+type ResponseRenderer func(r *http.Request, err error, status int) (contentType string, body []byte)
+
+// EmptyResponseRenderer is a ResponseRenderer that responds an empty
+// text/plain message. This is the default.
+func EmptyResponseRenderer(_ *http.Request, _ error, _ int) (string, []byte) {
+	return "text/plain", []byte{}
+}
+
+// JSONResponse is the response body of JSONResponseRenderer.
+type JSONResponse struct {
+	// Code is the effective meh.Code of the error.
+	Code meh.Code `json:"code"`
+	// MessageID is the meh.PublicMessage.ID of the error (see
+	// meh.PublicMessageOf).
+	MessageID string `json:"message_id,omitempty"`
+	// Message is the meh.PublicMessage of the error, translated using the
+	// meh.Catalog set via SetCatalog.
+	Message string `json:"message,omitempty"`
+	// Violations are the meh.Violation of the error (see meh.Violations).
+	Violations []meh.Violation `json:"violations,omitempty"`
+}
+
+// JSONResponseRenderer is a ResponseRenderer that responds a JSONResponse. It
+// holds the error code, public message and violations but no internal messages
+// or details as these might contain internal information. The public message
+// is translated to the language from the Accept-Language header (see
+// SetCatalog).
+func JSONResponseRenderer(r *http.Request, err error, _ int) (string, []byte) {
+	response := JSONResponse{
+		Code:       meh.ErrorCode(err),
+		Violations: meh.Violations(err),
+	}
+	if publicMessage, ok := meh.PublicMessageOf(err); ok {
+		response.MessageID = publicMessage.ID
+		response.Message, _ = TranslatePublicMessage(r, publicMessage)
+	}
+	body, _ := json.Marshal(response)
+	return "application/json", body
+}
+
+var (
+	// catalog is the meh.Catalog to use in TranslatePublicMessage.
+	catalog *meh.Catalog
+	// catalogMutex locks catalog.
+	catalogMutex sync.RWMutex
+)
+
+// SetCatalog sets the meh.Catalog that is used for translating a
+// meh.PublicMessage in TranslatePublicMessage. Per default, no catalog is set
+// and messages are not translated.
+func SetCatalog(c *meh.Catalog) {
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+	catalog = c
+}
+
+// TranslatePublicMessage translates the given meh.PublicMessage using the
+// meh.Catalog set via SetCatalog. The language is chosen from the
+// Accept-Language header of the given http.Request. If no catalog is set or
+// the message is not translated, false is returned.
+func TranslatePublicMessage(r *http.Request, publicMessage meh.PublicMessage) (string, bool) {
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+	if catalog == nil {
+		return "", false
+	}
+	lang := catalog.Match(r.Header.Get("Accept-Language"))
+	return catalog.Translate(lang, publicMessage)
+}
+
+var (
+	// responseRenderer is the ResponseRenderer to use in LogAndRespondError.
+	responseRenderer ResponseRenderer = EmptyResponseRenderer
+	// responseRendererMutex locks responseRenderer.
+	responseRendererMutex sync.RWMutex
+)
+
+// SetResponseRenderer sets the ResponseRenderer to use in LogAndRespondError.
+// Per default, EmptyResponseRenderer is used.
+func SetResponseRenderer(renderer ResponseRenderer) {
+	responseRendererMutex.Lock()
+	defer responseRendererMutex.Unlock()
+	responseRenderer = renderer
+}
+
+// RenderResponse renders the response for the given request, error and status
+// using the ResponseRenderer set via SetResponseRenderer. This is used in
+// LogAndRespondError and allows tests to compare responses.
+func RenderResponse(r *http.Request, err error, status int) (string, []byte) {
+	responseRendererMutex.RLock()
+	defer responseRendererMutex.RUnlock()
+	return responseRenderer(r, err, status)
+}
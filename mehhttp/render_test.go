@@ -0,0 +1,132 @@
+package mehhttp
+
+import (
+	"encoding/json"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ResponseRendererSuite tests SetResponseRenderer and the provided renderers.
+type ResponseRendererSuite struct {
+	suite.Suite
+	req *http.Request
+	rr  *httptest.ResponseRecorder
+	err error
+}
+
+func (suite *ResponseRendererSuite) SetupTest() {
+	suite.req = httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+	suite.rr = httptest.NewRecorder()
+	suite.err = meh.Wrap(meh.NewBadInputErr("hidden", meh.Details{
+		meh.DetailKeyViolations: []meh.Violation{{Path: "email", Constraint: "required"}},
+	}), "validate", meh.Details{
+		meh.DetailKeyViolations: []meh.Violation{{Path: "age", Constraint: "min", Message: "too young", Params: map[string]any{"min": float64(18)}}},
+	})
+}
+
+func (suite *ResponseRendererSuite) TearDownTest() {
+	SetResponseRenderer(EmptyResponseRenderer)
+}
+
+func (suite *ResponseRendererSuite) TestDefaultEmpty() {
+	LogAndRespondError(zap.NewNop(), suite.rr, suite.req, suite.err)
+	suite.Equal("text/plain", suite.rr.Header().Get("Content-Type"))
+	suite.Empty(suite.rr.Body.String(), "should respond empty body")
+}
+
+func (suite *ResponseRendererSuite) TestJSON() {
+	SetResponseRenderer(JSONResponseRenderer)
+	LogAndRespondError(zap.NewNop(), suite.rr, suite.req, suite.err)
+	suite.Equal("application/json", suite.rr.Header().Get("Content-Type"))
+	suite.NotContains(suite.rr.Body.String(), "hidden", "should not respond message")
+	var response JSONResponse
+	suite.Require().NoError(json.Unmarshal(suite.rr.Body.Bytes(), &response))
+	suite.Equal(JSONResponse{
+		Code: meh.ErrBadInput,
+		Violations: []meh.Violation{
+			{Path: "age", Constraint: "min", Message: "too young", Params: map[string]any{"min": float64(18)}},
+			{Path: "email", Constraint: "required"},
+		},
+	}, response)
+}
+
+func (suite *ResponseRendererSuite) TestJSONWithoutViolations() {
+	SetResponseRenderer(JSONResponseRenderer)
+	LogAndRespondError(zap.NewNop(), suite.rr, suite.req, meh.NewNotFoundErr("hidden", nil))
+	suite.JSONEq(`{"code":"not-found"}`, suite.rr.Body.String())
+}
+
+func TestResponseRenderer(t *testing.T) {
+	suite.Run(t, new(ResponseRendererSuite))
+}
+
+// TranslatePublicMessageSuite tests SetCatalog and TranslatePublicMessage along
+// with JSONResponseRenderer.
+type TranslatePublicMessageSuite struct {
+	suite.Suite
+	rr  *httptest.ResponseRecorder
+	err error
+}
+
+func (suite *TranslatePublicMessageSuite) SetupTest() {
+	c := meh.NewCatalog(language.English)
+	suite.Require().NoError(c.Load(language.English, map[string]string{
+		"user-not-found": "User {{.name}} not found.",
+	}))
+	suite.Require().NoError(c.Load(language.German, map[string]string{
+		"user-not-found": "Benutzer {{.name}} nicht gefunden.",
+	}))
+	SetCatalog(c)
+	SetResponseRenderer(JSONResponseRenderer)
+	suite.rr = httptest.NewRecorder()
+	suite.err = meh.WithPublicMessage(meh.NewNotFoundErr("user not found in db", nil),
+		"user-not-found", map[string]any{"name": "meh"})
+}
+
+func (suite *TranslatePublicMessageSuite) TearDownTest() {
+	SetCatalog(nil)
+	SetResponseRenderer(EmptyResponseRenderer)
+}
+
+// respond responds the error for a request with the given Accept-Language
+// header and returns the response.
+func (suite *TranslatePublicMessageSuite) respond(acceptLanguage string) JSONResponse {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080", nil)
+	req.Header.Set("Accept-Language", acceptLanguage)
+	LogAndRespondError(zap.NewNop(), suite.rr, req, suite.err)
+	var response JSONResponse
+	suite.Require().NoError(json.Unmarshal(suite.rr.Body.Bytes(), &response))
+	return response
+}
+
+func (suite *TranslatePublicMessageSuite) TestGerman() {
+	response := suite.respond("de-DE,de;q=0.9,en;q=0.8")
+	suite.Equal("user-not-found", response.MessageID)
+	suite.Equal("Benutzer meh nicht gefunden.", response.Message)
+}
+
+func (suite *TranslatePublicMessageSuite) TestFallback() {
+	response := suite.respond("fr")
+	suite.Equal("User meh not found.", response.Message, "should use fallback language")
+}
+
+func (suite *TranslatePublicMessageSuite) TestNoCatalog() {
+	SetCatalog(nil)
+	response := suite.respond("de")
+	suite.Equal("user-not-found", response.MessageID, "should still respond message id")
+	suite.Empty(response.Message, "should not respond message")
+}
+
+func (suite *TranslatePublicMessageSuite) TestInternalMessageNotResponded() {
+	suite.respond("de")
+	suite.NotContains(suite.rr.Body.String(), "db", "should not respond internal message")
+}
+
+func TestTranslatePublicMessage(t *testing.T) {
+	suite.Run(t, new(TranslatePublicMessageSuite))
+}
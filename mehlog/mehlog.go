@@ -5,6 +5,7 @@ import (
 	"github.com/lefinal/meh"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"sort"
 	"sync"
 )
 
@@ -20,6 +21,40 @@ func OmitErrorMessageField(omit bool) {
 	omitErrorMessageField = omit
 }
 
+// FieldErrorFingerprint is the field key for meh.Fingerprint in logs. It is
+// only added if enabled via IncludeFingerprintField.
+const FieldErrorFingerprint = "x_fingerprint"
+
+var includeFingerprintField = false
+var includeFingerprintFieldMutex sync.RWMutex
+
+// IncludeFingerprintField sets whether the meh.Fingerprint of logged errors
+// should be added as field with key FieldErrorFingerprint. This allows grouping
+// occurrences of the same kind of error in log pipelines.
+func IncludeFingerprintField(include bool) {
+	includeFingerprintFieldMutex.Lock()
+	defer includeFingerprintFieldMutex.Unlock()
+	includeFingerprintField = include
+}
+
+// FieldError is the field key for the nested error object if enabled via
+// UseNestedErrorField.
+const FieldError = "error"
+
+var useNestedErrorField = false
+var useNestedErrorFieldMutex sync.RWMutex
+
+// UseNestedErrorField sets whether the error should be logged as single nested
+// object with key FieldError instead of spreading details and metadata from
+// meh.ToMap to top-level fields. The object is built using
+// meh.Error.MarshalLogObject and holds the code, message, details of each
+// level and the stack trace in deterministic order.
+func UseNestedErrorField(use bool) {
+	useNestedErrorFieldMutex.Lock()
+	defer useNestedErrorFieldMutex.Unlock()
+	useNestedErrorField = use
+}
+
 var (
 	// defaultLevelTranslator is the default LevelTranslator that translates every
 	// meh.Code to zapcore.ErrorLevel.
@@ -41,6 +76,49 @@ func SetDefaultLevelTranslator(lt LevelTranslator) {
 // LevelTranslator translates the given meh.Code to zapcore.Level for logging.
 type LevelTranslator func(code meh.Code) zapcore.Level
 
+// Hook is called for each error that is logged via LogToLevel with the level
+// the error was logged to. Hooks are called before sampling, so errors that
+// are suppressed by the Sampler set via SetSampler are passed as well.
+type Hook func(err error, level zapcore.Level)
+
+var (
+	// hooks are the registered hooks that are called in LogToLevel.
+	hooks = make(map[int]Hook)
+	// nextHookID is the id for the next hook that is added to hooks.
+	nextHookID = 0
+	// hooksMutex locks hooks and nextHookID.
+	hooksMutex sync.RWMutex
+)
+
+// AddHook registers the given Hook to be called for each logged error. The
+// returned function removes the hook again.
+func AddHook(hook Hook) func() {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	id := nextHookID
+	nextHookID++
+	hooks[id] = hook
+	return func() {
+		hooksMutex.Lock()
+		defer hooksMutex.Unlock()
+		delete(hooks, id)
+	}
+}
+
+// callHooks calls all registered hooks with the given error and level. Hooks
+// are called without holding the lock, so that they may add or remove hooks.
+func callHooks(err error, level zapcore.Level) {
+	hooksMutex.RLock()
+	hooksToCall := make([]Hook, 0, len(hooks))
+	for _, hook := range hooks {
+		hooksToCall = append(hooksToCall, hook)
+	}
+	hooksMutex.RUnlock()
+	for _, hook := range hooksToCall {
+		hook(err, level)
+	}
+}
+
 // WrapAndLog calls Log after meh.Wrap with the given error and message.
 func WrapAndLog(logger *zap.Logger, err error, message string) {
 	Log(logger, meh.Wrap(err, message, nil))
@@ -56,23 +134,112 @@ func Log(logger *zap.Logger, err error) {
 	LogToLevel(logger, level, err)
 }
 
-// LogToLevel logs the given error to the given zapcore.Level.
+// LogToLevel logs the given error to the given zapcore.Level. If a Sampler is
+// set via SetSampler, the error might be suppressed.
 func LogToLevel(logger *zap.Logger, level zapcore.Level, err error) {
+	LogToEmitter(func(message string, fields []zap.Field) {
+		logToLevel(logger, level, message, fields...)
+	}, level, err)
+}
+
+// Emitter logs the given message with the given fields. It is bound to a logger
+// and level. Use FieldValues for logging the fields to loggers other than zap.
+type Emitter func(message string, fields []zap.Field)
+
+// LogToEmitter logs the given error with the given zapcore.Level using the given
+// Emitter. Like in LogToLevel, hooks are called, the Sampler set via SetSampler
+// is applied and fields respect settings like UseNestedErrorField and
+// SetStackTraceConfig. Summaries of the Sampler are logged using the Emitter as
+// well. Integrations for other loggers like mehzerolog use LogToEmitter with the
+// zapcore.Level that matches their own level.
+func LogToEmitter(emit Emitter, level zapcore.Level, err error) {
 	e := meh.Cast(err)
+	callHooks(e, level)
+	// Check if sampled.
+	samplerMutex.RLock()
+	sampler := sampler
+	samplerMutex.RUnlock()
+	if sampler != nil {
+		shouldLog, dueSummaries := sampler.sample(emit, e)
+		for _, dueSummary := range dueSummaries {
+			sampler.logSummary(dueSummary)
+		}
+		if !shouldLog {
+			return
+		}
+	}
 	// Build fields.
+	useNestedErrorFieldMutex.RLock()
+	useNestedErrorField := useNestedErrorField
+	useNestedErrorFieldMutex.RUnlock()
+	stackTraceConfig := currentStackTraceConfig()
+	var fields []zap.Field
+	if useNestedErrorField {
+		fields = []zap.Field{zap.Object(FieldError,
+			stackTraceConfig.withStackTraceVerbosity(e, stackTraceConfig.verbosity(e, level)))}
+		includeFingerprintFieldMutex.RLock()
+		includeFingerprintField := includeFingerprintField
+		includeFingerprintFieldMutex.RUnlock()
+		if includeFingerprintField {
+			fields = append(fields, zap.String(FieldErrorFingerprint, meh.Fingerprint(e)))
+		}
+	} else {
+		errFields := fieldMap(e)
+		keys := sortedKeys(errFields)
+		fields = make([]zap.Field, 0, len(keys)+1)
+		for _, k := range keys {
+			fields = append(fields, zap.Any(k, errFields[k]))
+		}
+		if stackTraceField, ok := stackTraceConfig.stackTraceField(e, level); ok {
+			fields = append(fields, stackTraceField)
+		}
+	}
+	// Log it.
+	emit(meh.LimitMessage(e.Error()), fields)
+}
+
+// FieldValues encodes the given fields and returns their keys in order along
+// with their values. Objects like the nested error field are encoded as maps.
+// Use it in an Emitter for logging the fields to loggers other than zap.
+func FieldValues(fields []zap.Field) ([]string, map[string]any) {
+	enc := zapcore.NewMapObjectEncoder()
+	keys := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field.AddTo(enc)
+		keys = append(keys, field.Key)
+	}
+	return keys, enc.Fields
+}
+
+// fieldMap returns the fields to log for the given error. These are the ones
+// from meh.ToMap while respecting OmitErrorMessageField and
+// IncludeFingerprintField.
+func fieldMap(err error) map[string]interface{} {
 	omitErrorMessageFieldMutex.RLock()
 	omitErrorMessageField := omitErrorMessageField
 	omitErrorMessageFieldMutex.RUnlock()
-	fieldMap := meh.ToMap(e)
-	fields := make([]zap.Field, 0, len(fieldMap))
-	for k, v := range fieldMap {
-		if omitErrorMessageField && k == meh.MapFieldErrorMessage {
-			continue
-		}
-		fields = append(fields, zap.Any(k, v))
+	includeFingerprintFieldMutex.RLock()
+	includeFingerprintField := includeFingerprintField
+	includeFingerprintFieldMutex.RUnlock()
+	m := meh.ToMap(err)
+	if omitErrorMessageField {
+		delete(m, meh.MapFieldErrorMessage)
 	}
-	// Log it.
-	logToLevel(logger, level, e.Error(), fields...)
+	if includeFingerprintField {
+		m[FieldErrorFingerprint] = meh.Fingerprint(err)
+	}
+	return m
+}
+
+// sortedKeys returns the keys of the given field map in sorted order. This
+// is used for logging fields in deterministic order.
+func sortedKeys(fieldMap map[string]interface{}) []string {
+	keys := make([]string, 0, len(fieldMap))
+	for k := range fieldMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // logToLevel calls the correct LogToLevel method for the given zap.Logger based on the
@@ -68,6 +68,19 @@ func (suite *LogSuite) TestDetails() {
 	suite.Contains(records[0].Fields, zap.Any("0/i_love", "cookies"), "should contain details from root error")
 }
 
+// TestRedactedDetails assures that details are redacted.
+func (suite *LogSuite) TestRedactedDetails() {
+	logger, rec := zaprec.NewRecorder(nil)
+	Log(logger, meh.NewInternalErr("meh", meh.Details{
+		"access_token": "abc",
+		"user":         meh.Secret{Value: "bob"},
+	}))
+	records := rec.Records()
+	suite.Require().Len(records, 1, "should have been logged")
+	suite.Contains(records[0].Fields, zap.Any("0/access_token", meh.RedactedValue), "should redact by key")
+	suite.Contains(records[0].Fields, zap.Any("0/user", meh.RedactedValue), "should redact secrets")
+}
+
 // TestErrorMessage assures that the correct log message is used.
 func (suite *LogSuite) TestErrorMessage() {
 	logger, rec := zaprec.NewRecorder(nil)
@@ -179,3 +192,102 @@ func (suite *logToLevelSuite) TestUnknownLevel() {
 func Test_logToLevel(t *testing.T) {
 	suite.Run(t, new(logToLevelSuite))
 }
+
+// TestIncludeFingerprintField tests IncludeFingerprintField.
+func TestIncludeFingerprintField(t *testing.T) {
+	IncludeFingerprintField(true)
+	defer IncludeFingerprintField(false)
+	logger, rec := zaprec.NewRecorder(nil)
+	e := meh.Wrap(meh.NewNotFoundErr("inner", meh.Details{"id": 42}), "outer", nil)
+	Log(logger, e)
+	records := rec.Records()
+	require.Len(t, records, 1, "should have been logged")
+	assert.Contains(t, records[0].Fields, zap.String(FieldErrorFingerprint, meh.Fingerprint(e)),
+		"should contain fingerprint")
+}
+
+// fingerprintErrA returns an error with stack trace for
+// TestFingerprintInAppFrames.
+func fingerprintErrA() error {
+	return meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil))
+}
+
+// fingerprintErrB returns an error with stack trace for
+// TestFingerprintInAppFrames.
+func fingerprintErrB() error {
+	return meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil))
+}
+
+// TestFingerprintInAppFrames tests that meh.Fingerprint only uses frames from
+// packages configured via meh.SetInAppPrefixes for errors from a package other
+// than meh.
+func TestFingerprintInAppFrames(t *testing.T) {
+	defer meh.SetInAppPrefixes(meh.InAppPrefixes()...)
+	meh.SetInAppPrefixes("github.com/lefinal/meh/mehlog")
+	assert.NotEqual(t, meh.Fingerprint(fingerprintErrA()), meh.Fingerprint(fingerprintErrB()),
+		"should include in-app frames")
+	meh.SetInAppPrefixes("example.com/app")
+	assert.Equal(t, meh.Fingerprint(fingerprintErrA()), meh.Fingerprint(fingerprintErrB()),
+		"should skip frames that are not in-app")
+}
+
+// TestAddHook tests AddHook.
+func TestAddHook(t *testing.T) {
+	var calledErr error
+	var calledLevel zapcore.Level
+	calls := 0
+	removeHook := AddHook(func(err error, level zapcore.Level) {
+		calls++
+		calledErr = err
+		calledLevel = level
+	})
+	e := meh.NewNotFoundErr("sad life", nil)
+	LogToLevel(zap.NewNop(), zapcore.WarnLevel, e)
+	assert.Equal(t, 1, calls, "should have called hook")
+	assert.Equal(t, e, calledErr, "should pass error")
+	assert.Equal(t, zapcore.WarnLevel, calledLevel, "should pass level")
+	removeHook()
+	LogToLevel(zap.NewNop(), zapcore.WarnLevel, e)
+	assert.Equal(t, 1, calls, "should not call removed hook")
+}
+
+// TestHookRemovesItself assures that hooks can remove themselves without
+// deadlocking.
+func TestHookRemovesItself(t *testing.T) {
+	calls := 0
+	var removeHook func()
+	removeHook = AddHook(func(_ error, _ zapcore.Level) {
+		calls++
+		removeHook()
+	})
+	LogToLevel(zap.NewNop(), zapcore.WarnLevel, meh.NewNotFoundErr("sad life", nil))
+	LogToLevel(zap.NewNop(), zapcore.WarnLevel, meh.NewNotFoundErr("sad life", nil))
+	assert.Equal(t, 1, calls, "should have called hook once")
+}
+
+// TestUseNestedErrorField tests UseNestedErrorField.
+func TestUseNestedErrorField(t *testing.T) {
+	UseNestedErrorField(true)
+	defer UseNestedErrorField(false)
+	logger, rec := zaprec.NewRecorder(nil)
+	e := meh.Wrap(meh.NewNotFoundErr("inner", meh.Details{"id": 42}), "outer", nil)
+	Log(logger, e)
+	records := rec.Records()
+	require.Len(t, records, 1, "should have been logged")
+	assert.Equal(t, []zap.Field{zap.Object(FieldError, e.(*meh.Error))}, records[0].Fields,
+		"should only contain nested error field")
+}
+
+// TestFieldMap tests fieldMap.
+func TestFieldMap(t *testing.T) {
+	e := meh.NewNotFoundErr("sad life", meh.Details{"id": 42})
+	IncludeFingerprintField(true)
+	OmitErrorMessageField(true)
+	defer IncludeFingerprintField(false)
+	defer OmitErrorMessageField(false)
+	assert.Equal(t, map[string]interface{}{
+		"0/id":                42,
+		meh.MapFieldErrorCode: meh.ErrNotFound,
+		FieldErrorFingerprint: meh.Fingerprint(e),
+	}, fieldMap(e))
+}
@@ -0,0 +1,40 @@
+package mehlog
+
+import (
+	"github.com/lefinal/meh"
+	"github.com/lefinal/zaprec"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentLogging assures that a shared error can be logged from several
+// goroutines without races. Run it with -race.
+func TestConcurrentLogging(t *testing.T) {
+	const goroutines = 16
+	const iterations = 50
+	shared := meh.Wrap(meh.ApplyStackTrace(meh.NewInternalErrFromErr(os.ErrNotExist, "shared", meh.Details{
+		"nested": meh.Details{"a": 1},
+	})), "wrap", meh.Details{"b": 2})
+	logger, rec := zaprec.NewRecorder(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(toLevel bool) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if toLevel {
+					LogToLevel(logger, zapcore.ErrorLevel, shared)
+				} else {
+					Log(logger, shared)
+				}
+				_ = fieldMap(shared)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	if got := len(rec.Records()); got != goroutines*iterations {
+		t.Errorf("should log all errors, got %d", got)
+	}
+}
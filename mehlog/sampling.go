@@ -0,0 +1,282 @@
+package mehlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/lefinal/meh"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Field keys used in summary lines of Sampler.
+const (
+	// FieldSamplingKey holds the key of the suppressed errors.
+	FieldSamplingKey = "x_sampling_key"
+	// FieldSuppressedCount holds the number of suppressed errors.
+	FieldSuppressedCount = "x_suppressed_count"
+	// FieldSamplingInterval holds the sampling interval.
+	FieldSamplingInterval = "x_sampling_interval"
+)
+
+// SummaryMessage is the log message for summary lines of Sampler.
+const SummaryMessage = "suppressed repeated errors"
+
+// SamplingKey returns the key for the given error that is used by Sampler for
+// grouping occurrences.
+type SamplingKey func(err error) string
+
+// Defaults for SamplingConfig.
+const (
+	// DefaultSamplingInterval is used for SamplingConfig.Interval if not set.
+	DefaultSamplingInterval = time.Minute
+	// DefaultSamplingFirst is used for SamplingConfig.First if not set.
+	DefaultSamplingFirst = 10
+	// DefaultSamplingMaxKeys is used for SamplingConfig.MaxKeys if not set.
+	DefaultSamplingMaxKeys = 10000
+)
+
+// SamplingKeyCodeAndMessages is a SamplingKey that groups errors by the
+// effective meh.Code and the messages of all meh.Error levels. Errors that are
+// not a meh.Error only contribute their type, as their messages often contain
+// interpolated values like IDs or addresses that would create a key for each
+// occurrence.
+func SamplingKeyCodeAndMessages(err error) string {
+	var b strings.Builder
+	b.WriteString(string(meh.ErrorCode(err)))
+	for it := meh.NewErrorUnwrapper(err); it.Next(); {
+		b.WriteString("\x00")
+		if e, ok := it.Current().(*meh.Error); ok {
+			b.WriteString(e.Message)
+		} else {
+			_, _ = fmt.Fprintf(&b, "%T", it.Current())
+		}
+	}
+	return b.String()
+}
+
+// SamplingKeyFingerprint is a SamplingKey that groups errors by
+// meh.Fingerprint.
+func SamplingKeyFingerprint(err error) string {
+	return meh.Fingerprint(err)
+}
+
+// SamplingConfig is the configuration for Sampler.
+type SamplingConfig struct {
+	// Interval is the duration of each sampling interval. If not positive,
+	// DefaultSamplingInterval is used.
+	Interval time.Duration
+	// First is the number of occurrences per key that are logged in each
+	// interval. Further occurrences are suppressed and reported in a summary line.
+	// If not positive, DefaultSamplingFirst is used.
+	First int
+	// PerCode optionally overrides First for errors with the given effective
+	// meh.Code. A negative value disables sampling for the code while zero
+	// suppresses all occurrences, so that only summary lines are logged.
+	PerCode map[meh.Code]int
+	// Key is used for grouping occurrences. If not set,
+	// SamplingKeyCodeAndMessages is used.
+	Key SamplingKey
+	// MaxKeys is the maximum number of keys that are tracked at the same time. If
+	// reached, keys whose interval has passed are evicted. If there are none,
+	// occurrences of new keys are logged without being tracked. If not positive,
+	// DefaultSamplingMaxKeys is used.
+	MaxKeys int
+}
+
+// samplingEntry holds the state of a single key in Sampler.
+type samplingEntry struct {
+	// intervalStart is the time the current interval started.
+	intervalStart time.Time
+	// count is the number of occurrences in the current interval.
+	count int
+	// suppressed is the number of suppressed occurrences in the current
+	// interval.
+	suppressed int
+	// lastErr is the last suppressed error that is used for the summary line.
+	lastErr error
+	// lastEmit is the Emitter that was used for logging lastErr. It is bound to
+	// the level of lastErr.
+	lastEmit Emitter
+}
+
+// Sampler limits logging of repeated errors. For each key, the first
+// occurrences per interval are logged and further ones are suppressed. The
+// number of suppressed occurrences is logged in a summary line when the
+// interval of the key has passed and the next occurrence is logged, or when
+// Flush is called. Use Run for periodically flushing. Enable a Sampler via
+// SetSampler.
+type Sampler struct {
+	config  SamplingConfig
+	now     func() time.Time
+	entries map[string]*samplingEntry
+	mutex   sync.Mutex
+}
+
+// NewSampler creates a new Sampler with the given SamplingConfig. Unset
+// fields are defaulted.
+func NewSampler(config SamplingConfig) *Sampler {
+	if config.Interval <= 0 {
+		config.Interval = DefaultSamplingInterval
+	}
+	if config.First <= 0 {
+		config.First = DefaultSamplingFirst
+	}
+	if config.Key == nil {
+		config.Key = SamplingKeyCodeAndMessages
+	}
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = DefaultSamplingMaxKeys
+	}
+	return &Sampler{
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]*samplingEntry),
+	}
+}
+
+// summary describes a summary line to log.
+type summary struct {
+	key        string
+	suppressed int
+	err        error
+	emit       Emitter
+}
+
+// sample decides whether the given error should be logged. Summaries that are
+// due for the previous interval or for evicted keys are returned as well.
+func (s *Sampler) sample(emit Emitter, err error) (bool, []*summary) {
+	limit := s.config.First
+	if perCode, ok := s.config.PerCode[meh.ErrorCode(err)]; ok {
+		limit = perCode
+	}
+	if limit < 0 {
+		return true, nil
+	}
+	key := s.config.Key(err)
+	now := s.now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var dueSummaries []*summary
+	entry, ok := s.entries[key]
+	if !ok {
+		if len(s.entries) >= s.config.MaxKeys {
+			dueSummaries = s.evictExpired(now)
+			if len(s.entries) >= s.config.MaxKeys {
+				return true, dueSummaries
+			}
+		}
+		entry = &samplingEntry{intervalStart: now}
+		s.entries[key] = entry
+	}
+	if now.Sub(entry.intervalStart) >= s.config.Interval {
+		if sum := entry.summary(key); sum != nil {
+			dueSummaries = append(dueSummaries, sum)
+		}
+		*entry = samplingEntry{intervalStart: now}
+	}
+	entry.count++
+	if entry.count <= limit {
+		return true, dueSummaries
+	}
+	entry.suppressed++
+	entry.lastErr = err
+	entry.lastEmit = emit
+	return false, dueSummaries
+}
+
+// evictExpired removes all entries whose interval has passed and returns their
+// summaries. The mutex must be held.
+func (s *Sampler) evictExpired(now time.Time) []*summary {
+	summaries := make([]*summary, 0)
+	for key, entry := range s.entries {
+		if now.Sub(entry.intervalStart) < s.config.Interval {
+			continue
+		}
+		if sum := entry.summary(key); sum != nil {
+			summaries = append(summaries, sum)
+		}
+		delete(s.entries, key)
+	}
+	return summaries
+}
+
+// summary returns the summary for the entry or nil if nothing was suppressed.
+func (entry *samplingEntry) summary(key string) *summary {
+	if entry.suppressed == 0 {
+		return nil
+	}
+	return &summary{
+		key:        key,
+		suppressed: entry.suppressed,
+		err:        entry.lastErr,
+		emit:       entry.lastEmit,
+	}
+}
+
+// Flush logs summary lines for all keys with suppressed occurrences and resets
+// their counts. Keys whose interval has passed are removed.
+func (s *Sampler) Flush() {
+	now := s.now()
+	s.mutex.Lock()
+	summaries := make([]*summary, 0)
+	for key, entry := range s.entries {
+		if sum := entry.summary(key); sum != nil {
+			summaries = append(summaries, sum)
+			entry.suppressed = 0
+			entry.lastErr = nil
+			entry.lastEmit = nil
+		}
+		if now.Sub(entry.intervalStart) >= s.config.Interval {
+			delete(s.entries, key)
+		}
+	}
+	s.mutex.Unlock()
+	for _, sum := range summaries {
+		s.logSummary(sum)
+	}
+}
+
+// Run calls Flush each interval until the given context.Context is done.
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.Flush()
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// logSummary logs the given summary to the level of the suppressed errors.
+func (s *Sampler) logSummary(sum *summary) {
+	e := meh.Cast(sum.err)
+	sum.emit(SummaryMessage, []zap.Field{
+		zap.String(FieldSamplingKey, sum.key),
+		zap.Int(FieldSuppressedCount, sum.suppressed),
+		zap.Duration(FieldSamplingInterval, s.config.Interval),
+		zap.Any(meh.MapFieldErrorCode, meh.ErrorCode(e)),
+		zap.String(meh.MapFieldErrorMessage, e.Error()),
+	})
+}
+
+var (
+	// sampler is the Sampler that is used in LogToLevel. If nil, sampling is
+	// disabled.
+	sampler *Sampler
+	// samplerMutex locks sampler.
+	samplerMutex sync.RWMutex
+)
+
+// SetSampler sets the Sampler that is used for each logged error. Set it to
+// nil in order to disable sampling, which is the default.
+func SetSampler(s *Sampler) {
+	samplerMutex.Lock()
+	defer samplerMutex.Unlock()
+	sampler = s
+}
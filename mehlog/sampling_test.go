@@ -0,0 +1,182 @@
+package mehlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/zaprec"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+// SamplerSuite tests Sampler.
+type SamplerSuite struct {
+	suite.Suite
+	now     time.Time
+	sampler *Sampler
+	logger  *zap.Logger
+	rec     *zaprec.RecordStore
+}
+
+func (suite *SamplerSuite) SetupTest() {
+	suite.now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.sampler = NewSampler(SamplingConfig{
+		Interval: time.Minute,
+		First:    2,
+		PerCode: map[meh.Code]int{
+			meh.ErrNotFound: 0,
+			meh.ErrInternal: -1,
+		},
+	})
+	suite.sampler.now = func() time.Time {
+		return suite.now
+	}
+	SetSampler(suite.sampler)
+	suite.logger, suite.rec = zaprec.NewRecorder(nil)
+}
+
+func (suite *SamplerSuite) TearDownTest() {
+	SetSampler(nil)
+}
+
+// summaries returns all logged summary lines.
+func (suite *SamplerSuite) summaries() []zaprec.Record {
+	summaries := make([]zaprec.Record, 0)
+	for _, record := range suite.rec.Records() {
+		if record.Entry.Message == SummaryMessage {
+			summaries = append(summaries, record)
+		}
+	}
+	return summaries
+}
+
+func (suite *SamplerSuite) TestLogFirst() {
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewBadInputErr("sad life", meh.Details{"i": i}))
+	}
+	suite.Len(suite.rec.Records(), 2, "should only log first occurrences")
+}
+
+func (suite *SamplerSuite) TestDifferentKeys() {
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+		Log(suite.logger, meh.NewBadInputErr("happy life", nil))
+	}
+	suite.Len(suite.rec.Records(), 4, "should sample each key separately")
+}
+
+func (suite *SamplerSuite) TestPerCode() {
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewNotFoundErr("sad life", nil))
+		Log(suite.logger, meh.NewInternalErr("sad life", nil))
+	}
+	suite.Len(suite.rec.Records(), 5, "should suppress all not-found and no internal errors")
+}
+
+func (suite *SamplerSuite) TestSummaryOnNextInterval() {
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+	}
+	suite.Empty(suite.summaries(), "should not log summary before interval passed")
+	suite.now = suite.now.Add(time.Minute)
+	Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+	summaries := suite.summaries()
+	suite.Require().Len(summaries, 1, "should log summary")
+	suite.Contains(summaries[0].Fields, zap.Int(FieldSuppressedCount, 3))
+	suite.Len(suite.rec.Records(), 4, "should log first occurrence of new interval")
+}
+
+func (suite *SamplerSuite) TestFlush() {
+	for i := 0; i < 5; i++ {
+		LogToLevel(suite.logger, zapcore.WarnLevel, meh.NewBadInputErr("sad life", nil))
+	}
+	suite.sampler.Flush()
+	summaries := suite.summaries()
+	suite.Require().Len(summaries, 1, "should log summary")
+	suite.Equal(zapcore.WarnLevel, summaries[0].Entry.Level, "should log summary to level of errors")
+	suite.Contains(summaries[0].Fields, zap.Int(FieldSuppressedCount, 3))
+	suite.Contains(summaries[0].Fields, zap.Any(meh.MapFieldErrorCode, meh.ErrBadInput))
+	suite.sampler.Flush()
+	suite.Len(suite.summaries(), 1, "should not log summary again")
+	LogToLevel(suite.logger, zapcore.WarnLevel, meh.NewBadInputErr("sad life", nil))
+	suite.Len(suite.rec.Records(), 3, "should keep suppressing in same interval")
+}
+
+func (suite *SamplerSuite) TestFlushRemovesExpired() {
+	Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+	suite.now = suite.now.Add(time.Minute)
+	suite.sampler.Flush()
+	suite.Empty(suite.sampler.entries, "should remove expired entries")
+}
+
+func (suite *SamplerSuite) TestFingerprintKey() {
+	suite.sampler.config.Key = SamplingKeyFingerprint
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewBadInputErrFromErr(meh.NewErr("", "sad life", meh.Details{"i": i}), "wrap", nil))
+	}
+	suite.Len(suite.rec.Records(), 2, "should group by fingerprint")
+}
+
+func (suite *SamplerSuite) TestHooksCalledForSuppressed() {
+	calls := 0
+	removeHook := AddHook(func(_ error, _ zapcore.Level) {
+		calls++
+	})
+	defer removeHook()
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+	}
+	suite.Equal(5, calls, "should call hooks for suppressed errors")
+}
+
+func (suite *SamplerSuite) TestDefaultFirst() {
+	sampler := NewSampler(SamplingConfig{})
+	sampler.now = suite.sampler.now
+	SetSampler(sampler)
+	for i := 0; i < DefaultSamplingFirst+1; i++ {
+		Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+	}
+	suite.Len(suite.rec.Records(), DefaultSamplingFirst, "should log first occurrences per default")
+}
+
+func (suite *SamplerSuite) TestDefaultInterval() {
+	sampler := NewSampler(SamplingConfig{First: 1})
+	suite.Equal(DefaultSamplingInterval, sampler.config.Interval, "should default interval")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.NotPanics(func() {
+		sampler.Run(ctx)
+	}, "should not panic")
+}
+
+func (suite *SamplerSuite) TestKeyIgnoresForeignMessages() {
+	for i := 0; i < 5; i++ {
+		Log(suite.logger, meh.NewBadInputErrFromErr(fmt.Errorf("sad life %d", i), "wrap", nil))
+	}
+	suite.Len(suite.rec.Records(), 2, "should group by meh messages")
+	suite.Len(suite.sampler.entries, 1, "should track single key")
+}
+
+func (suite *SamplerSuite) TestMaxKeys() {
+	suite.sampler.config.MaxKeys = 2
+	for i := 0; i < 3; i++ {
+		Log(suite.logger, meh.NewBadInputErr("sad life", nil))
+	}
+	Log(suite.logger, meh.NewBadInputErr("happy life", nil))
+	Log(suite.logger, meh.NewBadInputErr("meh life", nil))
+	Log(suite.logger, meh.NewBadInputErr("meh life", nil))
+	Log(suite.logger, meh.NewBadInputErr("meh life", nil))
+	suite.Len(suite.sampler.entries, 2, "should not track more keys")
+	suite.Len(suite.rec.Records(), 6, "should log untracked keys")
+	suite.now = suite.now.Add(time.Minute)
+	Log(suite.logger, meh.NewBadInputErr("meh life", nil))
+	suite.Len(suite.summaries(), 1, "should log summary of evicted key")
+	suite.Len(suite.sampler.entries, 1, "should evict expired keys")
+}
+
+func TestSampler(t *testing.T) {
+	suite.Run(t, new(SamplerSuite))
+}
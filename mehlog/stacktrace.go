@@ -0,0 +1,204 @@
+package mehlog
+
+import (
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// mehFuncPrefix is the prefix of function names of the meh package.
+const mehFuncPrefix = "github.com/lefinal/meh."
+
+// FieldStackTrace is the default field key for stack traces. It is the same
+// key that zap uses for its own stack traces, so that log viewers render them
+// as such. Use StackTraceConfig.FieldKey if it collides with stack traces
+// added by zap.
+const FieldStackTrace = "stacktrace"
+
+// DefaultStackTraceTopFrames is the default for StackTraceConfig.TopFrames.
+const DefaultStackTraceTopFrames = 5
+
+// StackTraceVerbosity describes how much of a stack trace is logged.
+type StackTraceVerbosity int
+
+const (
+	// StackTraceNone does not log stack traces.
+	StackTraceNone StackTraceVerbosity = iota
+	// StackTraceTop only logs the top frames of stack traces. The number of
+	// frames is set with StackTraceConfig.TopFrames. Frames of meh itself like
+	// from meh.ApplyStackTrace are skipped.
+	StackTraceTop
+	// StackTraceFull logs complete stack traces.
+	StackTraceFull
+)
+
+// StackTraceVerbosityDecider returns the StackTraceVerbosity for an error with
+// the given effective meh.Code that is logged to the given zapcore.Level.
+type StackTraceVerbosityDecider func(code meh.Code, level zapcore.Level) StackTraceVerbosity
+
+// StackTraceConfig configures logging of stack traces that were applied using
+// meh.ApplyStackTrace.
+type StackTraceConfig struct {
+	// Verbosity decides how much of stack traces is logged. If not set,
+	// StackTraceNone is used.
+	Verbosity StackTraceVerbosityDecider
+	// TopFrames is the number of frames to log for StackTraceTop. If not set,
+	// DefaultStackTraceTopFrames is used.
+	TopFrames int
+	// AllTraces describes whether the stack traces of all levels should be logged
+	// instead of only the deepest one. This only applies to regular fields and not
+	// to the nested error object (see UseNestedErrorField), which always holds the
+	// deepest stack trace.
+	AllTraces bool
+	// FieldKey is the field key for stack traces. If not set, FieldStackTrace is
+	// used.
+	FieldKey string
+}
+
+var (
+	// stackTraceConfig is the StackTraceConfig used in LogToLevel.
+	stackTraceConfig = StackTraceConfig{}
+	// stackTraceConfigMutex locks stackTraceConfig.
+	stackTraceConfigMutex sync.RWMutex
+)
+
+// SetStackTraceConfig sets the StackTraceConfig to use for each logged error.
+// Per default, stack traces are not logged.
+func SetStackTraceConfig(config StackTraceConfig) {
+	stackTraceConfigMutex.Lock()
+	defer stackTraceConfigMutex.Unlock()
+	stackTraceConfig = config
+}
+
+// StackTraceVerbosityForLevel returns a StackTraceVerbosityDecider that uses
+// the given StackTraceVerbosity for each error that is logged to at least the
+// given zapcore.Level and StackTraceNone for all others.
+func StackTraceVerbosityForLevel(minLevel zapcore.Level, verbosity StackTraceVerbosity) StackTraceVerbosityDecider {
+	return func(_ meh.Code, level zapcore.Level) StackTraceVerbosity {
+		if level >= minLevel {
+			return verbosity
+		}
+		return StackTraceNone
+	}
+}
+
+// currentStackTraceConfig returns the current StackTraceConfig with defaults
+// applied.
+func currentStackTraceConfig() StackTraceConfig {
+	stackTraceConfigMutex.RLock()
+	config := stackTraceConfig
+	stackTraceConfigMutex.RUnlock()
+	if config.TopFrames <= 0 {
+		config.TopFrames = DefaultStackTraceTopFrames
+	}
+	if config.FieldKey == "" {
+		config.FieldKey = FieldStackTrace
+	}
+	return config
+}
+
+// verbosity returns the StackTraceVerbosity for the given error and level.
+func (config StackTraceConfig) verbosity(err error, level zapcore.Level) StackTraceVerbosity {
+	if config.Verbosity == nil {
+		return StackTraceNone
+	}
+	return config.Verbosity(meh.ErrorCode(err), level)
+}
+
+// stackTraceField returns the field holding the stack trace of the given error
+// based on the StackTraceConfig. If no stack trace is to be logged, false is
+// returned.
+func (config StackTraceConfig) stackTraceField(e *meh.Error, level zapcore.Level) (zap.Field, bool) {
+	verbosity := config.verbosity(e, level)
+	if verbosity == StackTraceNone {
+		return zap.Field{}, false
+	}
+	var formatted string
+	if config.AllTraces {
+		segments := make([]string, 0)
+		for it := meh.NewErrorUnwrapper(e); it.Next(); {
+			current, ok := it.Current().(*meh.Error)
+			if !ok || current.Trace.StackTrace == nil {
+				continue
+			}
+			segments = append(segments, fmt.Sprintf("level %d:\n%s", it.Level(),
+				formatStackTrace(config.limit(current.Trace.StackTrace, verbosity))))
+		}
+		formatted = strings.Join(segments, "\n")
+	} else if stackTrace := e.StackTrace(); stackTrace != nil {
+		formatted = formatStackTrace(config.limit(stackTrace, verbosity))
+	}
+	if formatted == "" {
+		return zap.Field{}, false
+	}
+	return zap.String(config.FieldKey, formatted), true
+}
+
+// limit limits the given errors.StackTrace based on the StackTraceVerbosity.
+// For StackTraceTop, leading frames of meh are skipped before limiting.
+func (config StackTraceConfig) limit(trace errors.StackTrace, verbosity StackTraceVerbosity) errors.StackTrace {
+	if verbosity != StackTraceTop {
+		return trace
+	}
+	trace = skipMehFrames(trace)
+	if len(trace) > config.TopFrames {
+		return trace[:config.TopFrames]
+	}
+	return trace
+}
+
+// skipMehFrames returns the given errors.StackTrace without leading frames of
+// the meh package. If all frames belong to meh, the stack trace is returned as
+// is.
+func skipMehFrames(stackTrace errors.StackTrace) errors.StackTrace {
+	for i, frame := range stackTrace {
+		// Frame is the program counter + 1, like in errors.Frame.
+		fn := runtime.FuncForPC(uintptr(frame) - 1)
+		if fn == nil || !strings.HasPrefix(fn.Name(), mehFuncPrefix) {
+			return stackTrace[i:]
+		}
+	}
+	return stackTrace
+}
+
+// formatStackTrace formats the given errors.StackTrace like zap does with
+// function names and file locations on separate lines.
+func formatStackTrace(stackTrace errors.StackTrace) string {
+	return strings.TrimPrefix(fmt.Sprintf("%+v", stackTrace), "\n")
+}
+
+// withStackTraceVerbosity returns a copy of the given error with stack traces
+// removed or limited based on the StackTraceVerbosity. This is used for the
+// nested error object that holds the deepest stack trace. The given error is
+// not altered.
+func (config StackTraceConfig) withStackTraceVerbosity(e *meh.Error, verbosity StackTraceVerbosity) *meh.Error {
+	if verbosity == StackTraceFull {
+		return e
+	}
+	var root *meh.Error
+	var prev *meh.Error
+	for it := meh.NewErrorUnwrapper(e); it.Next(); {
+		current, ok := it.Current().(*meh.Error)
+		if !ok {
+			break
+		}
+		copied := *current
+		if verbosity == StackTraceNone {
+			copied.Trace = meh.StackTrace{}
+		} else {
+			copied.Trace.StackTrace = config.limit(copied.Trace.StackTrace, verbosity)
+		}
+		if prev == nil {
+			root = &copied
+		} else {
+			prev.WrappedErr = &copied
+		}
+		prev = &copied
+	}
+	return root
+}
@@ -0,0 +1,150 @@
+package mehlog
+
+import (
+	"github.com/lefinal/meh"
+	"github.com/lefinal/zaprec"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+)
+
+// StackTraceSuite tests logging of stack traces with StackTraceConfig.
+type StackTraceSuite struct {
+	suite.Suite
+	logger *zap.Logger
+	rec    *zaprec.RecordStore
+	err    error
+}
+
+func (suite *StackTraceSuite) SetupTest() {
+	suite.logger, suite.rec = zaprec.NewRecorder(nil)
+	suite.err = meh.Wrap(meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil)), "outer", nil)
+}
+
+func (suite *StackTraceSuite) TearDownTest() {
+	SetStackTraceConfig(StackTraceConfig{})
+	UseNestedErrorField(false)
+}
+
+// stackTraceField logs the error and returns the value of the stack trace
+// field with the given key.
+func (suite *StackTraceSuite) stackTraceField(key string) (string, bool) {
+	Log(suite.logger, suite.err)
+	records := suite.rec.Records()
+	suite.Require().Len(records, 1, "should have been logged")
+	for _, field := range records[0].Fields {
+		if field.Key == key {
+			return field.String, true
+		}
+	}
+	return "", false
+}
+
+func (suite *StackTraceSuite) TestDefaultNone() {
+	_, ok := suite.stackTraceField(FieldStackTrace)
+	suite.False(ok, "should not log stack trace per default")
+}
+
+func (suite *StackTraceSuite) TestFull() {
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.ErrorLevel, StackTraceFull),
+	})
+	stackTrace, ok := suite.stackTraceField("stacktrace")
+	suite.Require().True(ok, "should log stack trace in the field of zap")
+	suite.Equal(formatStackTrace(suite.err.(*meh.Error).StackTrace()), stackTrace)
+	suite.Contains(stackTrace, "StackTraceSuite", "should contain test function")
+}
+
+func (suite *StackTraceSuite) TestTop() {
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.ErrorLevel, StackTraceTop),
+		TopFrames: 2,
+	})
+	stackTrace, ok := suite.stackTraceField(FieldStackTrace)
+	suite.Require().True(ok, "should log stack trace")
+	suite.Equal(formatStackTrace(skipMehFrames(suite.err.(*meh.Error).StackTrace())[:2]), stackTrace)
+	suite.Len(strings.Split(stackTrace, "\n"), 4, "should only log top frames with function and file")
+	suite.True(strings.HasPrefix(stackTrace, "github.com/lefinal/meh/mehlog.(*StackTraceSuite)"),
+		"should skip frames of meh")
+}
+
+func (suite *StackTraceSuite) TestLevelBelowMin() {
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.DPanicLevel, StackTraceFull),
+	})
+	_, ok := suite.stackTraceField(FieldStackTrace)
+	suite.False(ok, "should not log stack trace for lower levels")
+}
+
+func (suite *StackTraceSuite) TestPerCode() {
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: func(code meh.Code, _ zapcore.Level) StackTraceVerbosity {
+			if code == meh.ErrInternal {
+				return StackTraceFull
+			}
+			return StackTraceNone
+		},
+	})
+	_, ok := suite.stackTraceField(FieldStackTrace)
+	suite.True(ok, "should log stack trace for code")
+}
+
+func (suite *StackTraceSuite) TestCustomFieldKey() {
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.DebugLevel, StackTraceFull),
+		FieldKey:  "trace",
+	})
+	_, ok := suite.stackTraceField("trace")
+	suite.True(ok, "should use custom field key")
+}
+
+func (suite *StackTraceSuite) TestAllTraces() {
+	suite.err = meh.ApplyStackTrace(meh.Wrap(meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil)), "outer", nil))
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.DebugLevel, StackTraceTop),
+		AllTraces: true,
+	})
+	stackTrace, ok := suite.stackTraceField(FieldStackTrace)
+	suite.Require().True(ok, "should log stack trace")
+	suite.Contains(stackTrace, "level 0:\n", "should contain trace of top level")
+	suite.Contains(stackTrace, "level 1:\n", "should contain trace of wrapped level")
+}
+
+func (suite *StackTraceSuite) TestNoStackTrace() {
+	suite.err = meh.NewInternalErr("sad life", nil)
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.DebugLevel, StackTraceFull),
+	})
+	_, ok := suite.stackTraceField(FieldStackTrace)
+	suite.False(ok, "should not log empty stack trace")
+}
+
+func (suite *StackTraceSuite) TestNestedNone() {
+	UseNestedErrorField(true)
+	Log(suite.logger, suite.err)
+	records := suite.rec.Records()
+	suite.Require().Len(records, 1, "should have been logged")
+	enc := zapcore.NewMapObjectEncoder()
+	records[0].Fields[0].AddTo(enc)
+	suite.NotContains(enc.Fields[FieldError], meh.LogObjectKeyStackTrace, "should strip stack trace")
+	suite.NotNil(suite.err.(*meh.Error).StackTrace(), "should not alter original error")
+}
+
+func (suite *StackTraceSuite) TestNestedFull() {
+	UseNestedErrorField(true)
+	SetStackTraceConfig(StackTraceConfig{
+		Verbosity: StackTraceVerbosityForLevel(zapcore.DebugLevel, StackTraceFull),
+	})
+	Log(suite.logger, suite.err)
+	records := suite.rec.Records()
+	suite.Require().Len(records, 1, "should have been logged")
+	enc := zapcore.NewMapObjectEncoder()
+	records[0].Fields[0].AddTo(enc)
+	suite.Contains(enc.Fields[FieldError], meh.LogObjectKeyStackTrace, "should keep stack trace")
+}
+
+func TestStackTrace(t *testing.T) {
+	suite.Run(t, new(StackTraceSuite))
+}
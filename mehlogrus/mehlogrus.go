@@ -0,0 +1,102 @@
+// Package mehlogrus allows logging of meh.Error to logrus.FieldLogger with the
+// same semantics and field names as mehlog.
+package mehlogrus
+
+import (
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehlog"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+)
+
+var (
+	// defaultLevelTranslator is the default LevelTranslator that translates every
+	// meh.Code to logrus.ErrorLevel.
+	defaultLevelTranslator LevelTranslator = func(code meh.Code) logrus.Level {
+		return logrus.ErrorLevel
+	}
+	// defaultLevelTranslatorMutex locks defaultLevelTranslator.
+	defaultLevelTranslatorMutex sync.RWMutex
+)
+
+// SetDefaultLevelTranslator sets the LevelTranslator to be used for regular
+// Log-calls.
+func SetDefaultLevelTranslator(lt LevelTranslator) {
+	defaultLevelTranslatorMutex.Lock()
+	defer defaultLevelTranslatorMutex.Unlock()
+	defaultLevelTranslator = lt
+}
+
+// LevelTranslator translates the given meh.Code to logrus.Level for logging.
+type LevelTranslator func(code meh.Code) logrus.Level
+
+// WrapAndLog calls Log after meh.Wrap with the given error and message.
+func WrapAndLog(logger logrus.FieldLogger, err error, message string) {
+	Log(logger, meh.Wrap(err, message, nil))
+}
+
+// Log the given error using the default level translator that can be set via
+// SetDefaultLevelTranslator.
+func Log(logger logrus.FieldLogger, err error) {
+	e := meh.Cast(err)
+	defaultLevelTranslatorMutex.RLock()
+	level := defaultLevelTranslator(meh.ErrorCode(e))
+	defaultLevelTranslatorMutex.RUnlock()
+	LogToLevel(logger, level, err)
+}
+
+// LogToLevel logs the given error to the given logrus.Level. It uses
+// mehlog.LogToEmitter, so that fields, hooks and sampling are the same as in
+// mehlog and respect settings like mehlog.UseNestedErrorField or
+// mehlog.SetStackTraceConfig.
+func LogToLevel(logger logrus.FieldLogger, level logrus.Level, err error) {
+	mehlog.LogToEmitter(func(message string, fields []zap.Field) {
+		_, values := mehlog.FieldValues(fields)
+		logToLevel(logger.WithFields(values), level, message)
+	}, zapLevel(level), err)
+}
+
+// zapLevel returns the zapcore.Level that matches the given logrus.Level. It
+// is passed to hooks and used for deciding about stack traces in mehlog.
+func zapLevel(level logrus.Level) zapcore.Level {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return zapcore.DebugLevel
+	case logrus.InfoLevel:
+		return zapcore.InfoLevel
+	case logrus.WarnLevel:
+		return zapcore.WarnLevel
+	case logrus.PanicLevel:
+		return zapcore.PanicLevel
+	case logrus.FatalLevel:
+		return zapcore.FatalLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// logToLevel calls the correct logging method for the given logrus.Level. Like
+// in mehlog, logrus.PanicLevel panics and logrus.FatalLevel exits after logging
+// while unknown levels are logged to logrus.ErrorLevel.
+func logToLevel(entry *logrus.Entry, level logrus.Level, message string) {
+	switch level {
+	case logrus.TraceLevel:
+		entry.Trace(message)
+	case logrus.DebugLevel:
+		entry.Debug(message)
+	case logrus.InfoLevel:
+		entry.Info(message)
+	case logrus.WarnLevel:
+		entry.Warn(message)
+	case logrus.ErrorLevel:
+		entry.Error(message)
+	case logrus.PanicLevel:
+		entry.Panic(message)
+	case logrus.FatalLevel:
+		entry.Fatal(message)
+	default:
+		entry.Error(message)
+	}
+}
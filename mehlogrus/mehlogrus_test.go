@@ -0,0 +1,144 @@
+package mehlogrus
+
+import (
+	"errors"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehlog"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+	"testing"
+)
+
+// LogSuite tests Log.
+type LogSuite struct {
+	suite.Suite
+	logger *logrus.Logger
+	hook   *test.Hook
+}
+
+func (suite *LogSuite) SetupTest() {
+	suite.logger, suite.hook = test.NewNullLogger()
+	suite.logger.SetLevel(logrus.TraceLevel)
+}
+
+func (suite *LogSuite) TestNilError() {
+	Log(suite.logger, nil)
+	suite.Len(suite.hook.AllEntries(), 1, "should be logged")
+}
+
+func (suite *LogSuite) TestNonMehError() {
+	Log(suite.logger, errors.New("sad life"))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Equal(logrus.ErrorLevel, entries[0].Level)
+	suite.Equal("sad life", entries[0].Message)
+}
+
+func (suite *LogSuite) TestDetails() {
+	Log(suite.logger, meh.Wrap(&meh.Error{Code: meh.ErrNotFound, Details: meh.Details{"hello": "world"}}, "ola",
+		meh.Details{"i_love": "cookies"}))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Equal("world", entries[0].Data["1/hello"], "should contain details from root error")
+	suite.Equal("cookies", entries[0].Data["0/i_love"], "should contain details from wrapper")
+	suite.Equal(meh.ErrNotFound, entries[0].Data[meh.MapFieldErrorCode], "should contain code")
+	suite.Equal("ola", entries[0].Data[meh.MapFieldErrorMessage], "should contain error message")
+}
+
+func (suite *LogSuite) TestFieldLoggerEntry() {
+	Log(suite.logger.WithField("request_id", "abc"), meh.NewInternalErr("sad life", nil))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Equal("abc", entries[0].Data["request_id"], "should keep existing fields")
+}
+
+func (suite *LogSuite) TestOmitErrorMessageField() {
+	mehlog.OmitErrorMessageField(true)
+	defer mehlog.OmitErrorMessageField(false)
+	Log(suite.logger, meh.NewInternalErr("sad life", nil))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.NotContains(entries[0].Data, meh.MapFieldErrorMessage, "should respect mehlog settings")
+}
+
+func (suite *LogSuite) TestLevelTranslator() {
+	SetDefaultLevelTranslator(func(code meh.Code) logrus.Level {
+		if code == meh.ErrNotFound {
+			return logrus.DebugLevel
+		}
+		return logrus.ErrorLevel
+	})
+	defer SetDefaultLevelTranslator(func(_ meh.Code) logrus.Level {
+		return logrus.ErrorLevel
+	})
+	Log(suite.logger, meh.NewNotFoundErr("sad life", nil))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Equal(logrus.DebugLevel, entries[0].Level)
+}
+
+func (suite *LogSuite) TestWrapAndLog() {
+	WrapAndLog(suite.logger, &meh.Error{Message: "inner"}, "outer")
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Equal("outer: inner", entries[0].Message)
+}
+
+func (suite *LogSuite) TestLevels() {
+	for _, level := range []logrus.Level{logrus.TraceLevel, logrus.DebugLevel, logrus.InfoLevel,
+		logrus.WarnLevel, logrus.ErrorLevel} {
+		suite.hook.Reset()
+		LogToLevel(suite.logger, level, meh.NewInternalErr("sad life", nil))
+		entries := suite.hook.AllEntries()
+		suite.Require().Len(entries, 1, "should be logged")
+		suite.Equal(level, entries[0].Level)
+	}
+}
+
+func (suite *LogSuite) TestPanicLevel() {
+	suite.Panics(func() {
+		LogToLevel(suite.logger, logrus.PanicLevel, meh.NewInternalErr("sad life", nil))
+	})
+}
+
+func (suite *LogSuite) TestUnknownLevel() {
+	LogToLevel(suite.logger, logrus.Level(42), meh.NewInternalErr("sad life", nil))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Equal(logrus.ErrorLevel, entries[0].Level)
+}
+
+func (suite *LogSuite) TestNestedErrorField() {
+	mehlog.UseNestedErrorField(true)
+	defer mehlog.UseNestedErrorField(false)
+	Log(suite.logger, meh.NewInternalErr("sad life", meh.Details{"hello": "world"}))
+	entries := suite.hook.AllEntries()
+	suite.Require().Len(entries, 1, "should be logged")
+	suite.Contains(entries[0].Data, mehlog.FieldError, "should log nested error field")
+	suite.NotContains(entries[0].Data, "0/hello", "should not spread details")
+}
+
+func (suite *LogSuite) TestHooks() {
+	var level zapcore.Level
+	removeHook := mehlog.AddHook(func(_ error, l zapcore.Level) {
+		level = l
+	})
+	defer removeHook()
+	LogToLevel(suite.logger, logrus.WarnLevel, meh.NewInternalErr("sad life", nil))
+	suite.Equal(zapcore.WarnLevel, level, "should call hooks with matching level")
+}
+
+func (suite *LogSuite) TestSampler() {
+	mehlog.SetSampler(mehlog.NewSampler(mehlog.SamplingConfig{First: 1}))
+	defer mehlog.SetSampler(nil)
+	for i := 0; i < 3; i++ {
+		Log(suite.logger, meh.NewInternalErr("sad life", nil))
+	}
+	suite.Len(suite.hook.AllEntries(), 1, "should suppress repeated errors")
+}
+
+func TestLog(t *testing.T) {
+	suite.Run(t, new(LogSuite))
+}
@@ -0,0 +1,134 @@
+// Package mehmetrics provides Prometheus metrics for errors by meh.Code.
+package mehmetrics
+
+import (
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/meh/mehlog"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"runtime"
+)
+
+// Outcome describes what happened with an observed error.
+type Outcome string
+
+const (
+	// OutcomeLogged is used for errors that were logged via mehlog. This
+	// includes errors that were suppressed by the mehlog.Sampler, so that
+	// counts are accurate even when logs are sampled.
+	OutcomeLogged Outcome = "logged"
+	// OutcomeResponded is used for errors that were responded via
+	// mehhttp.LogAndRespondError.
+	OutcomeResponded Outcome = "responded"
+)
+
+// Label names used in the errors counter.
+const (
+	LabelCode      = "code"
+	LabelComponent = "component"
+	LabelOutcome   = "outcome"
+)
+
+// ComponentUnknown is the component label value if the ComponentResolver
+// returned an empty string.
+const ComponentUnknown = "unknown"
+
+// ComponentResolver returns the component label value for the given error.
+// Keep in mind that the number of different values should be small as each one
+// creates a new time series.
+type ComponentResolver func(err error) string
+
+// Options for NewCollector.
+type Options struct {
+	// Namespace is the optional namespace for metric names.
+	Namespace string
+	// Subsystem is the optional subsystem for metric names.
+	Subsystem string
+	// ComponentResolver is used for determining the component label value. If not
+	// set, SourcePackage is used.
+	ComponentResolver ComponentResolver
+}
+
+// Collector counts errors by effective meh.Code, component and Outcome. It
+// implements prometheus.Collector and therefore needs to be registered to a
+// prometheus.Registerer. Errors are counted via Observe or automatically
+// after calling Hook.
+type Collector struct {
+	errors            *prometheus.CounterVec
+	componentResolver ComponentResolver
+}
+
+// NewCollector creates a new Collector with the given Options.
+func NewCollector(options Options) *Collector {
+	componentResolver := options.ComponentResolver
+	if componentResolver == nil {
+		componentResolver = SourcePackage
+	}
+	return &Collector{
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Subsystem: options.Subsystem,
+			Name:      "meh_errors_total",
+			Help:      "Number of errors by effective code, component and outcome.",
+		}, []string{LabelCode, LabelComponent, LabelOutcome}),
+		componentResolver: componentResolver,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(descs chan<- *prometheus.Desc) {
+	c.errors.Describe(descs)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(metrics chan<- prometheus.Metric) {
+	c.errors.Collect(metrics)
+}
+
+// Observe counts the given error with the Outcome.
+func (c *Collector) Observe(err error, outcome Outcome) {
+	component := c.componentResolver(err)
+	if component == "" {
+		component = ComponentUnknown
+	}
+	c.errors.WithLabelValues(string(meh.ErrorCode(err)), component, string(outcome)).Inc()
+}
+
+// Hook registers hooks via mehlog.AddHook and mehhttp.AddRespondHook so that
+// each logged error is observed with OutcomeLogged and each responded one with
+// OutcomeResponded. The returned function removes the hooks again.
+func (c *Collector) Hook() func() {
+	removeLogHook := mehlog.AddHook(func(err error, _ zapcore.Level) {
+		c.Observe(err, OutcomeLogged)
+	})
+	removeRespondHook := mehhttp.AddRespondHook(func(_ *http.Request, err error, _ int) {
+		c.Observe(err, OutcomeResponded)
+	})
+	return func() {
+		removeLogHook()
+		removeRespondHook()
+	}
+}
+
+// SourcePackage is a ComponentResolver that returns the package of the first
+// in-app frame (see meh.IsInAppPackage) in the deepest stack trace of the
+// error. If no stack trace was applied using meh.ApplyStackTrace or no frame is
+// in-app, an empty string is returned.
+func SourcePackage(err error) string {
+	e, ok := err.(*meh.Error)
+	if !ok {
+		return ""
+	}
+	for _, frame := range e.StackTrace() {
+		fn := runtime.FuncForPC(uintptr(frame) - 1)
+		if fn == nil {
+			continue
+		}
+		if pkg := meh.FuncPackage(fn.Name()); meh.IsInAppPackage(pkg) {
+			return pkg
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package mehmetrics
+
+import (
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/lefinal/meh/mehlog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// CollectorSuite tests Collector.
+type CollectorSuite struct {
+	suite.Suite
+	collector *Collector
+}
+
+func (suite *CollectorSuite) SetupTest() {
+	suite.collector = NewCollector(Options{
+		ComponentResolver: func(err error) string {
+			if meh.ErrorCode(err) == meh.ErrNotFound {
+				return "users"
+			}
+			return ""
+		},
+	})
+}
+
+// count returns the counter value for the given labels.
+func (suite *CollectorSuite) count(code meh.Code, component string, outcome Outcome) float64 {
+	return testutil.ToFloat64(suite.collector.errors.WithLabelValues(string(code), component, string(outcome)))
+}
+
+func (suite *CollectorSuite) TestObserve() {
+	suite.collector.Observe(meh.NewNotFoundErr("sad life", nil), OutcomeLogged)
+	suite.collector.Observe(meh.Wrap(meh.NewNotFoundErr("sad life", nil), "wrap", nil), OutcomeLogged)
+	suite.collector.Observe(meh.NewInternalErr("sad life", nil), OutcomeResponded)
+	suite.Equal(float64(2), suite.count(meh.ErrNotFound, "users", OutcomeLogged))
+	suite.Equal(float64(1), suite.count(meh.ErrInternal, ComponentUnknown, OutcomeResponded))
+}
+
+func (suite *CollectorSuite) TestRegister() {
+	registry := prometheus.NewPedanticRegistry()
+	suite.Require().NoError(registry.Register(suite.collector), "register should not fail")
+	suite.collector.Observe(meh.NewInternalErr("sad life", nil), OutcomeLogged)
+	err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP meh_errors_total Number of errors by effective code, component and outcome.
+# TYPE meh_errors_total counter
+meh_errors_total{code="internal",component="unknown",outcome="logged"} 1
+`), "meh_errors_total")
+	suite.NoError(err, "should expose metrics")
+}
+
+func (suite *CollectorSuite) TestHook() {
+	removeHooks := suite.collector.Hook()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	mehhttp.LogAndRespondError(zap.NewNop(), httptest.NewRecorder(), req, meh.NewNotFoundErr("sad life", nil))
+	mehlog.Log(zap.NewNop(), meh.NewInternalErr("sad life", nil))
+	suite.Equal(float64(1), suite.count(meh.ErrNotFound, "users", OutcomeResponded))
+	suite.Equal(float64(1), suite.count(meh.ErrNotFound, "users", OutcomeLogged))
+	suite.Equal(float64(1), suite.count(meh.ErrInternal, ComponentUnknown, OutcomeLogged))
+	removeHooks()
+	mehlog.Log(zap.NewNop(), meh.NewInternalErr("sad life", nil))
+	suite.Equal(float64(1), suite.count(meh.ErrInternal, ComponentUnknown, OutcomeLogged),
+		"should not observe after removing hooks")
+}
+
+func TestCollector(t *testing.T) {
+	suite.Run(t, new(CollectorSuite))
+}
+
+// TestSourcePackage tests SourcePackage.
+func TestSourcePackage(t *testing.T) {
+	assert.Empty(t, SourcePackage(meh.NewInternalErr("sad life", nil)), "should return empty without trace")
+	pkg := SourcePackage(meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil)))
+	assert.Equal(t, "github.com/lefinal/meh/mehmetrics", pkg, "should return package of caller")
+}
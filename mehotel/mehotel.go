@@ -0,0 +1,164 @@
+// Package mehotel provides recording of meh.Error on OpenTelemetry spans.
+package mehotel
+
+import (
+	"context"
+	"fmt"
+	"github.com/lefinal/meh"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+	"sync"
+)
+
+// Keys for details that are added in RecordError for log correlation.
+const (
+	DetailsKeyTraceID = "otel_trace_id"
+	DetailsKeySpanID  = "otel_span_id"
+)
+
+// Attribute keys used for exception events in RecordError.
+const (
+	// EventNameException is the name of the event that is added to spans.
+	EventNameException = "exception"
+	// AttributeExceptionType holds the effective meh.Code.
+	AttributeExceptionType = "exception.type"
+	// AttributeExceptionMessage holds the error message.
+	AttributeExceptionMessage = "exception.message"
+	// AttributeExceptionStacktrace holds the formatted stack trace if one was
+	// applied using meh.ApplyStackTrace.
+	AttributeExceptionStacktrace = "exception.stacktrace"
+	// AttributeCode holds the effective meh.Code.
+	AttributeCode = "meh.code"
+	// AttributeDetailsPrefix is the prefix for flattened details. The suffix is
+	// the key as in meh.ToMap.
+	AttributeDetailsPrefix = "meh.details."
+)
+
+// SpanStatusMapper maps a meh.Code to the status code that is set on spans in
+// RecordError. If codes.Unset is returned, the status is not changed.
+type SpanStatusMapper func(code meh.Code) codes.Code
+
+var (
+	// spanStatusMapper is the SpanStatusMapper used in RecordError.
+	spanStatusMapper SpanStatusMapper = DefaultSpanStatusMapper
+	// spanStatusMapperMutex locks spanStatusMapper.
+	spanStatusMapperMutex sync.RWMutex
+)
+
+// DefaultSpanStatusMapper is the default SpanStatusMapper. It does not mark
+// spans as failed for client errors like meh.ErrBadInput, meh.ErrNotFound,
+// meh.ErrConflict, meh.ErrUnauthorized and meh.ErrForbidden and uses
+// codes.Error for all others.
+func DefaultSpanStatusMapper(code meh.Code) codes.Code {
+	switch code {
+	case meh.ErrBadInput, meh.ErrNotFound, meh.ErrConflict, meh.ErrUnauthorized, meh.ErrForbidden:
+		return codes.Unset
+	default:
+		return codes.Error
+	}
+}
+
+// SetSpanStatusMapping sets the SpanStatusMapper that is used in RecordError.
+func SetSpanStatusMapping(mapper SpanStatusMapper) {
+	spanStatusMapperMutex.Lock()
+	defer spanStatusMapperMutex.Unlock()
+	spanStatusMapper = mapper
+}
+
+// RecordError records the given error on the span. It adds an exception event
+// with the effective meh.Code, the error message, flattened details and the
+// stack trace if available. The span status is set based on the mapping set
+// via SetSpanStatusMapping. The returned error holds the trace and span id in
+// details with keys DetailsKeyTraceID and DetailsKeySpanID for log
+// correlation. If the error is nil, nil is returned.
+func RecordError(span trace.Span, err error) error {
+	if err == nil {
+		return nil
+	}
+	code := meh.ErrorCode(err)
+	if span.IsRecording() {
+		span.AddEvent(EventNameException, trace.WithAttributes(Attributes(err)...))
+		spanStatusMapperMutex.RLock()
+		status := spanStatusMapper(code)
+		spanStatusMapperMutex.RUnlock()
+		if status != codes.Unset {
+			span.SetStatus(status, err.Error())
+		}
+	}
+	spanContext := span.SpanContext()
+	if !spanContext.IsValid() {
+		return err
+	}
+	return meh.ApplyDetails(err, meh.Details{
+		DetailsKeyTraceID: spanContext.TraceID().String(),
+		DetailsKeySpanID:  spanContext.SpanID().String(),
+	})
+}
+
+// RecordErrorFromContext calls RecordError with the span from the given
+// context.Context.
+func RecordErrorFromContext(ctx context.Context, err error) error {
+	return RecordError(trace.SpanFromContext(ctx), err)
+}
+
+// Attributes returns the attributes for the given error as used in RecordError.
+// Details are flattened using the keys from meh.ToMap and sorted by key.
+func Attributes(err error) []attribute.KeyValue {
+	e := meh.Cast(err)
+	code := string(meh.ErrorCode(e))
+	attributes := []attribute.KeyValue{
+		attribute.String(AttributeExceptionType, code),
+		attribute.String(AttributeExceptionMessage, e.Error()),
+		attribute.String(AttributeCode, code),
+	}
+	if stackTrace := deepestStackTrace(e); stackTrace != "" {
+		attributes = append(attributes, attribute.String(AttributeExceptionStacktrace, stackTrace))
+	}
+	fieldMap := meh.ToMap(e)
+	delete(fieldMap, meh.MapFieldErrorCode)
+	delete(fieldMap, meh.MapFieldErrorMessage)
+	keys := make([]string, 0, len(fieldMap))
+	for k := range fieldMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attributes = append(attributes, detailAttribute(AttributeDetailsPrefix+k, fieldMap[k]))
+	}
+	return attributes
+}
+
+// deepestStackTrace returns the formatted stack trace of the deepest level that
+// has one.
+func deepestStackTrace(err error) string {
+	var stackTrace string
+	for it := meh.NewErrorUnwrapper(err); it.Next(); {
+		if e, ok := it.Current().(*meh.Error); ok && e.Trace.StackTraceStr != "" {
+			stackTrace = e.Trace.StackTraceStr
+		}
+	}
+	return stackTrace
+}
+
+// detailAttribute creates an attribute.KeyValue for the given detail value.
+// Basic types are kept and everything else is formatted as string.
+func detailAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprintf("%+v", v))
+	}
+}
@@ -0,0 +1,119 @@
+package mehotel
+
+import (
+	"context"
+	"errors"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+// RecordErrorSuite tests RecordError.
+type RecordErrorSuite struct {
+	suite.Suite
+	exporter *tracetest.InMemoryExporter
+	tracer   trace.Tracer
+}
+
+func (suite *RecordErrorSuite) SetupTest() {
+	suite.exporter = tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(suite.exporter))
+	suite.tracer = provider.Tracer("mehotel-test")
+}
+
+// record starts a span, records the given error and returns the resulting
+// error as well as the ended span.
+func (suite *RecordErrorSuite) record(err error) (error, tracetest.SpanStub) {
+	ctx, span := suite.tracer.Start(context.Background(), "test")
+	recorded := RecordErrorFromContext(ctx, err)
+	span.End()
+	spans := suite.exporter.GetSpans()
+	suite.Require().Len(spans, 1, "should have exported span")
+	return recorded, spans[0]
+}
+
+// attributeMap returns the attributes of the given event as map.
+func attributeMap(attributes []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, a := range attributes {
+		m[a.Key] = a.Value
+	}
+	return m
+}
+
+func (suite *RecordErrorSuite) TestNil() {
+	recorded, span := suite.record(nil)
+	suite.Nil(recorded, "should return nil")
+	suite.Empty(span.Events, "should not add events")
+}
+
+func (suite *RecordErrorSuite) TestEvent() {
+	err := meh.Wrap(meh.NewInternalErrFromErr(errors.New("sad life"), "query db", meh.Details{
+		"query": "SELECT 1",
+		"limit": 10,
+	}), "get users", nil)
+	_, span := suite.record(err)
+	suite.Require().Len(span.Events, 1, "should add exception event")
+	event := span.Events[0]
+	suite.Equal(EventNameException, event.Name)
+	attributes := attributeMap(event.Attributes)
+	suite.Equal(string(meh.ErrInternal), attributes[AttributeExceptionType].AsString())
+	suite.Equal(string(meh.ErrInternal), attributes[AttributeCode].AsString())
+	suite.Equal(err.Error(), attributes[AttributeExceptionMessage].AsString())
+	suite.Equal("SELECT 1", attributes[AttributeDetailsPrefix+"1/query"].AsString())
+	suite.Equal(int64(10), attributes[AttributeDetailsPrefix+"1/limit"].AsInt64())
+	suite.NotContains(attributes, attribute.Key(AttributeExceptionStacktrace), "should not set empty stack trace")
+}
+
+func (suite *RecordErrorSuite) TestStackTrace() {
+	_, span := suite.record(meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil)))
+	suite.Require().Len(span.Events, 1, "should add exception event")
+	suite.NotEmpty(attributeMap(span.Events[0].Attributes)[AttributeExceptionStacktrace].AsString())
+}
+
+func (suite *RecordErrorSuite) TestInternalErrorStatus() {
+	_, span := suite.record(meh.NewInternalErr("sad life", nil))
+	suite.Equal(codes.Error, span.Status.Code, "should mark span as failed")
+	suite.Equal("sad life", span.Status.Description)
+}
+
+func (suite *RecordErrorSuite) TestClientErrorStatus() {
+	_, span := suite.record(meh.NewNotFoundErr("sad life", nil))
+	suite.Equal(codes.Unset, span.Status.Code, "should not mark span as failed")
+}
+
+func (suite *RecordErrorSuite) TestConflictStatus() {
+	_, span := suite.record(meh.NewConflictErr("sad life", nil))
+	suite.Equal(codes.Unset, span.Status.Code, "should not mark span as failed")
+}
+
+func (suite *RecordErrorSuite) TestCustomStatusMapping() {
+	SetSpanStatusMapping(func(_ meh.Code) codes.Code {
+		return codes.Error
+	})
+	defer SetSpanStatusMapping(DefaultSpanStatusMapper)
+	_, span := suite.record(meh.NewNotFoundErr("sad life", nil))
+	suite.Equal(codes.Error, span.Status.Code, "should use custom mapping")
+}
+
+func (suite *RecordErrorSuite) TestCorrelationDetails() {
+	recorded, span := suite.record(meh.NewInternalErr("sad life", nil))
+	e := recorded.(*meh.Error)
+	suite.Equal(span.SpanContext.TraceID().String(), e.Details[DetailsKeyTraceID])
+	suite.Equal(span.SpanContext.SpanID().String(), e.Details[DetailsKeySpanID])
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(recorded), "should keep code")
+}
+
+func (suite *RecordErrorSuite) TestNoSpan() {
+	err := meh.NewInternalErr("sad life", nil)
+	suite.Equal(err, RecordErrorFromContext(context.Background(), err), "should return error as is")
+}
+
+func TestRecordError(t *testing.T) {
+	suite.Run(t, new(RecordErrorSuite))
+}
@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/jackc/pgconn"
+	pgxv4 "github.com/jackc/pgx/v4"
+	pgxv5 "github.com/jackc/pgx/v5"
 	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
 	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehsql"
 	"strings"
+	"sync"
 )
 
 // Prefixes for PostgreSQL error codes.
@@ -17,100 +21,319 @@ const (
 	ErrCodePrefixDataException                  = "22"
 	ErrCodePrefixIntegrityConstraintViolation   = "23"
 	ErrCodePrefixSyntaxErrOrAccessRuleViolation = "42"
+	ErrCodePrefixConnectionException            = "08"
+	ErrCodePrefixInsufficientResources          = "53"
 )
 
-// NewQueryDBErr creates a new meh.Error with the given error and message and
-// sets a field in details to the provided query. If the error is related to
-// constraint violation or data exceptions, a meh.ErrBadInput will be returned.
-// Otherwise, meh.ErrInternal.
-func NewQueryDBErr(err error, message string, query string, args ...any) error {
-	var finalDetailedErr error
-	details := make(meh.Details)
-	details["query"] = query
-	details["args"] = args
-	// Check if postgres error.
+// PostgreSQL error codes with special handling in DefaultSQLStateRules.
+//
+// See: https://www.postgresql.org/docs/13/errcodes-appendix.html.
+const (
+	ErrCodeForeignKeyViolation  = "23503"
+	ErrCodeUniqueViolation      = "23505"
+	ErrCodeSerializationFailure = "40001"
+	ErrCodeDeadlockDetected     = "40P01"
+	ErrCodeQueryCanceled        = "57014"
+)
+
+// ErrRetryable is used for errors where the operation failed because of
+// concurrent transactions, like serialization failures or deadlocks. Retrying
+// the transaction is likely to succeed.
+const ErrRetryable meh.Code = "mehpg-retryable"
+
+// SQLStateRule maps an SQLSTATE to a meh.Code and message for NewQueryDBErr.
+type SQLStateRule struct {
+	// SQLState is either a complete 5-character error code like "23505" or an
+	// error class prefix like "23".
+	SQLState string
+	// Code is the meh.Code to use for matching errors.
+	Code meh.Code
+	// Message is the message for the error level with the Code.
+	Message string
+}
+
+// DefaultSQLStateRules returns the default rules used in NewQueryDBErr.
+func DefaultSQLStateRules() []SQLStateRule {
+	return []SQLStateRule{
+		{SQLState: ErrCodeUniqueViolation, Code: meh.ErrConflict, Message: "unique violation"},
+		{SQLState: ErrCodeForeignKeyViolation, Code: meh.ErrBadInput, Message: "foreign key violation"},
+		{SQLState: ErrCodePrefixIntegrityConstraintViolation, Code: meh.ErrBadInput, Message: "constraint violation"},
+		{SQLState: ErrCodePrefixDataException, Code: meh.ErrBadInput, Message: "data exception"},
+		{SQLState: ErrCodePrefixSyntaxErrOrAccessRuleViolation, Code: meh.ErrInternal, Message: "syntax error"},
+		{SQLState: ErrCodeSerializationFailure, Code: ErrRetryable, Message: "serialization failure"},
+		{SQLState: ErrCodeDeadlockDetected, Code: ErrRetryable, Message: "deadlock detected"},
+		{SQLState: ErrCodeQueryCanceled, Code: meh.ErrCanceled, Message: "query canceled"},
+		{SQLState: ErrCodePrefixConnectionException, Code: meh.ErrUnavailable, Message: "connection exception"},
+		{SQLState: ErrCodePrefixInsufficientResources, Code: meh.ErrUnavailable, Message: "insufficient resources"},
+	}
+}
+
+var (
+	// sqlStateRules are the rules used in NewQueryDBErr.
+	sqlStateRules = DefaultSQLStateRules()
+	// sqlStateRulesMutex locks sqlStateRules.
+	sqlStateRulesMutex sync.RWMutex
+)
+
+// SetSQLStateRules sets the rules to use in NewQueryDBErr. An exact match of
+// the SQLSTATE takes precedence over class prefixes and longer prefixes take
+// precedence over shorter ones. Errors without a matching rule result in
+// meh.ErrInternal. In order to extend the defaults, append to the ones from
+// DefaultSQLStateRules.
+func SetSQLStateRules(rules []SQLStateRule) {
+	sqlStateRulesMutex.Lock()
+	defer sqlStateRulesMutex.Unlock()
+	sqlStateRules = append([]SQLStateRule(nil), rules...)
+}
+
+// classifySQLState returns the rule with the longest match for the given
+// SQLSTATE. If no rule matches, false is returned.
+func classifySQLState(sqlState string) (SQLStateRule, bool) {
+	sqlStateRulesMutex.RLock()
+	defer sqlStateRulesMutex.RUnlock()
+	var best SQLStateRule
+	found := false
+	for _, rule := range sqlStateRules {
+		if rule.SQLState == "" || !strings.HasPrefix(sqlState, rule.SQLState) {
+			continue
+		}
+		if !found || len(rule.SQLState) > len(best.SQLState) {
+			best = rule
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Keys for details extracted from PostgreSQL errors in NewQueryDBErr. Only
+// non-empty values are added. Values for DetailKeyDetail and DetailKeyHint are
+// redacted using RedactionPolicy.DefaultAction.
+const (
+	DetailKeyQuery      = mehsql.DetailKeyQuery
+	DetailKeyArgs       = mehsql.DetailKeyArgs
+	DetailKeySQLState   = "sqlstate"
+	DetailKeyConstraint = "pg_constraint"
+	DetailKeySchema     = "pg_schema"
+	DetailKeyTable      = "pg_table"
+	DetailKeyColumn     = "pg_column"
+	DetailKeyDataType   = "pg_data_type"
+	DetailKeyDetail     = "pg_detail"
+	DetailKeyHint       = "pg_hint"
+	// DetailKeyField is the key for the field of a ConstraintMessage.
+	DetailKeyField = "field"
+	// DetailKeyResource is the key for the resource description passed to
+	// NewQueryAndScanRowsErrForResource.
+	DetailKeyResource = "resource"
+)
+
+// IsNoRows checks whether the given error is or wraps sql.ErrNoRows or
+// ErrNoRows from pgx v4 or v5, which are returned when QueryRow().Scan() finds
+// no rows.
+func IsNoRows(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgxv4.ErrNoRows) || errors.Is(err, pgxv5.ErrNoRows)
+}
+
+// ConstraintMessage is a user-facing message for violations of a named
+// constraint.
+type ConstraintMessage struct {
+	// Field is the name of the field the constraint applies to, like "email".
+	Field string
+	// Message is the user-facing message, like "email already taken".
+	Message string
+}
+
+var (
+	// constraintMessages holds the registered ConstraintMessage by constraint
+	// name.
+	constraintMessages = make(map[string]ConstraintMessage)
+	// constraintMessagesMutex locks constraintMessages.
+	constraintMessagesMutex sync.RWMutex
+)
+
+// RegisterConstraintMessage registers a user-facing message for violations of
+// the constraint with the given name. For example, the unique constraint
+// "users_email_key" might be registered with field "email" and message "email
+// already taken". Integrity constraint violations of this constraint then
+// result in meh.ErrBadInput with the message and the field in details with key
+// DetailKeyField instead of the code from the SQLSTATE rules. As the message is
+// meant for users, a meh.Violation with the field as path, the constraint name
+// and the message is added with key meh.DetailKeyViolations, so that responses
+// of mehhttp include it. Registering a constraint again overwrites the previous
+// message.
+func RegisterConstraintMessage(constraint string, field string, message string) {
+	constraintMessagesMutex.Lock()
+	defer constraintMessagesMutex.Unlock()
+	constraintMessages[constraint] = ConstraintMessage{
+		Field:   field,
+		Message: message,
+	}
+}
+
+// UnregisterConstraintMessage removes the message for the constraint with the
+// given name that was registered via RegisterConstraintMessage.
+func UnregisterConstraintMessage(constraint string) {
+	constraintMessagesMutex.Lock()
+	defer constraintMessagesMutex.Unlock()
+	delete(constraintMessages, constraint)
+}
+
+// lookupConstraintMessage returns the ConstraintMessage registered for the
+// given constraint name.
+func lookupConstraintMessage(constraint string) (ConstraintMessage, bool) {
+	constraintMessagesMutex.RLock()
+	defer constraintMessagesMutex.RUnlock()
+	constraintMessage, ok := constraintMessages[constraint]
+	return constraintMessage, ok
+}
+
+// pgErrInfo holds the relevant information from PostgreSQL errors, regardless
+// of the pgconn version.
+type pgErrInfo struct {
+	sqlState   string
+	constraint string
+	schema     string
+	table      string
+	column     string
+	dataType   string
+	detail     string
+	hint       string
+}
+
+// Classifier is a mehsql.Classifier for errors from pgconn v4 and pgx v5.
+// PostgreSQL errors are classified using the rules set via SetSQLStateRules and
+// constraint messages registered via RegisterConstraintMessage. Extracted
+// information is added to details using the DetailKey-constants. No-rows
+// errors from pgx result in meh.ErrNotFound. Register it via
+// mehsql.RegisterClassifier when using mehsql with a PostgreSQL driver.
+var Classifier mehsql.Classifier = mehsql.ClassifierFunc(classify)
+
+// classify implements Classifier.
+func classify(err error) (mehsql.Classification, bool) {
 	var pgErr *pgconn.PgError
 	var pgErrV5 *pgconnv5.PgError
 	if errors.As(err, &pgErr) {
-		details["pg_err"] = *pgErr
-		details["sqlstate"] = pgErr.Code
-		// Check for certain prefixes.
-		if strings.HasPrefix(pgErr.Code, ErrCodePrefixIntegrityConstraintViolation) {
-			// Constraint violation.
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrBadInput,
-				Message:    "constraint violation",
-				WrappedErr: err,
-			}
-		} else if strings.HasPrefix(pgErr.Code, ErrCodePrefixDataException) {
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrBadInput,
-				Message:    "data exception",
-				WrappedErr: err,
-			}
-		} else if strings.HasPrefix(pgErr.Code, ErrCodePrefixSyntaxErrOrAccessRuleViolation) {
-			// Syntax error
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrInternal,
-				Message:    "syntax error",
-				WrappedErr: err,
-			}
-		} else {
-			// Otherwise, probably internal error.
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrInternal,
-				WrappedErr: err,
-			}
-		}
+		return classifyPgErr(pgErrInfo{
+			sqlState:   pgErr.Code,
+			constraint: pgErr.ConstraintName,
+			schema:     pgErr.SchemaName,
+			table:      pgErr.TableName,
+			column:     pgErr.ColumnName,
+			dataType:   pgErr.DataTypeName,
+			detail:     pgErr.Detail,
+			hint:       pgErr.Hint,
+		}), true
 	} else if errors.As(err, &pgErrV5) {
-		details["pg_err"] = *pgErrV5
-		details["sqlstate"] = pgErrV5.Code
-		// Check for certain prefixes.
-		if strings.HasPrefix(pgErrV5.Code, ErrCodePrefixIntegrityConstraintViolation) {
-			// Constraint violation.
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrBadInput,
-				Message:    "constraint violation",
-				WrappedErr: err,
-			}
-		} else if strings.HasPrefix(pgErrV5.Code, ErrCodePrefixDataException) {
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrBadInput,
-				Message:    "data exception",
-				WrappedErr: err,
-			}
-		} else if strings.HasPrefix(pgErrV5.Code, ErrCodePrefixSyntaxErrOrAccessRuleViolation) {
-			// Syntax error
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrInternal,
-				Message:    "syntax error",
-				WrappedErr: err,
-			}
-		} else {
-			// Otherwise, probably internal error.
-			finalDetailedErr = &meh.Error{
-				Code:       meh.ErrInternal,
-				WrappedErr: err,
-			}
+		return classifyPgErr(pgErrInfo{
+			sqlState:   pgErrV5.Code,
+			constraint: pgErrV5.ConstraintName,
+			schema:     pgErrV5.SchemaName,
+			table:      pgErrV5.TableName,
+			column:     pgErrV5.ColumnName,
+			dataType:   pgErrV5.DataTypeName,
+			detail:     pgErrV5.Detail,
+			hint:       pgErrV5.Hint,
+		}), true
+	} else if IsNoRows(err) {
+		return mehsql.Classification{Code: meh.ErrNotFound, Message: "no rows"}, true
+	}
+	return mehsql.Classification{}, false
+}
+
+// classifyPgErr returns the mehsql.Classification for the given pgErrInfo
+// with extracted information in details.
+func classifyPgErr(info pgErrInfo) mehsql.Classification {
+	details := meh.Details{DetailKeySQLState: info.sqlState}
+	for k, v := range map[string]string{
+		DetailKeyConstraint: info.constraint,
+		DetailKeySchema:     info.schema,
+		DetailKeyTable:      info.table,
+		DetailKeyColumn:     info.column,
+		DetailKeyDataType:   info.dataType,
+	} {
+		if v != "" {
+			details[k] = v
 		}
-	} else if errors.Is(err, sql.ErrTxDone) {
-		finalDetailedErr = &meh.Error{
-			Code:       meh.ErrInternal,
-			Message:    "tx done",
-			WrappedErr: err,
+	}
+	if info.detail != "" {
+		details[DetailKeyDetail] = redactPgMessage(info.detail)
+	}
+	if info.hint != "" {
+		details[DetailKeyHint] = redactPgMessage(info.hint)
+	}
+	// Check for registered constraint.
+	if info.constraint != "" && strings.HasPrefix(info.sqlState, ErrCodePrefixIntegrityConstraintViolation) {
+		if constraintMessage, ok := lookupConstraintMessage(info.constraint); ok {
+			details[DetailKeyField] = constraintMessage.Field
+			details[meh.DetailKeyViolations] = []meh.Violation{{
+				Path:       constraintMessage.Field,
+				Constraint: info.constraint,
+				Message:    constraintMessage.Message,
+			}}
+			return mehsql.Classification{
+				Code:    meh.ErrBadInput,
+				Message: constraintMessage.Message,
+				Details: details,
+			}
 		}
-	} else if errors.Is(err, sql.ErrConnDone) {
-		finalDetailedErr = &meh.Error{
-			Code:       meh.ErrInternal,
-			Message:    "connection done",
-			WrappedErr: err,
+	}
+	rule, ok := classifySQLState(info.sqlState)
+	if !ok {
+		// Otherwise, probably internal error.
+		return mehsql.Classification{
+			Code:    meh.ErrInternal,
+			Details: details,
 		}
 	}
-	if finalDetailedErr != nil && meh.ErrorCode(finalDetailedErr) != meh.ErrNeutral {
-		return meh.Wrap(finalDetailedErr, message, details)
+	return mehsql.Classification{
+		Code:    rule.Code,
+		Message: rule.Message,
+		Details: details,
 	}
-	// Any other internal error.
-	return meh.NewInternalErrFromErr(err, message, details)
+}
+
+// NewQueryDBErr creates a new meh.Error with the given error and message and
+// sets a field in details to the provided query and redacted args (see
+// SetRedactionPolicy). The meh.Code for PostgreSQL errors is determined using
+// the rules that can be set via SetSQLStateRules (see Classifier). Per default,
+// constraint violations and data exceptions result in meh.ErrBadInput, unique
+// violations in meh.ErrConflict, serialization failures and deadlocks in
+// ErrRetryable, canceled queries in meh.ErrCanceled and connection problems in
+// meh.ErrUnavailable. Otherwise, meh.ErrInternal. No-rows errors (see
+// IsNoRows) result in meh.ErrInternal as well. Use NewQueryAndScanRowsErr for
+// QueryRow with Scan or IsNoRows for handling them as meh.ErrNotFound. Other
+// errors are classified using mehsql.Classify.
+// Constraint violations for constraints registered via
+// RegisterConstraintMessage result in meh.ErrBadInput with the registered
+// message. Available information from the PostgreSQL error, like the
+// constraint name, is added to details using the DetailKey-constants.
+func NewQueryDBErr(err error, message string, query string, args ...any) error {
+	return newQueryDBErr(err, message, queryDetails(make(meh.Details), query, args), false)
+}
+
+// queryDetails adds the given query and args to the given details. Args are
+// redacted based on the RedactionPolicy set via SetRedactionPolicy and omitted
+// for sensitive queries.
+func queryDetails(details meh.Details, query string, args []any) meh.Details {
+	details[DetailKeyQuery] = query
+	if redactedArgs, ok := RedactArgs(query, args); ok {
+		details[DetailKeyArgs] = redactedArgs
+	}
+	return details
+}
+
+// newQueryDBErr creates the error for NewQueryDBErr with the given details
+// using Classifier and falling back to mehsql.Classify. No-rows errors (see
+// IsNoRows) only result in meh.ErrNotFound if noRowsAsNotFound is set.
+// Otherwise, they result in meh.ErrInternal.
+func newQueryDBErr(err error, message string, details meh.Details, noRowsAsNotFound bool) error {
+	if !noRowsAsNotFound && IsNoRows(err) {
+		return meh.NewInternalErrFromErr(err, message, details)
+	}
+	classification, ok := Classifier.Classify(err)
+	if !ok {
+		classification, ok = mehsql.Classify(err)
+	}
+	return mehsql.NewErr(err, message, classification, ok, details)
 }
 
 // NewScanRowsErr creates a new meh.ErrInternal with the given error and message
@@ -121,7 +344,17 @@ func NewScanRowsErr(err error, message string, query string, args ...any) error
 }
 
 // NewQueryAndScanRowsErr is used for errors returned from QueryRow with Scan.
-// Further logic might be added in the future.
+// The logic is the same as NewQueryDBErr except for no-rows errors (see
+// IsNoRows) from database/sql, pgx v4 and pgx v5 resulting in meh.ErrNotFound.
 func NewQueryAndScanRowsErr(err error, message string, query string) error {
-	return NewQueryDBErr(err, message, query)
+	return newQueryDBErr(err, message, queryDetails(make(meh.Details), query, nil), true)
+}
+
+// NewQueryAndScanRowsErrForResource is the same as NewQueryAndScanRowsErr but
+// adds the given resource description, like "user 42", to details with key
+// DetailKeyResource as well as the given args. This allows for readable
+// not-found errors.
+func NewQueryAndScanRowsErrForResource(err error, message string, resource string, query string, args ...any) error {
+	details := queryDetails(meh.Details{DetailKeyResource: resource}, query, args)
+	return newQueryDBErr(err, message, details, true)
 }
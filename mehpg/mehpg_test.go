@@ -1,9 +1,18 @@
 package mehpg
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	pgxv4 "github.com/jackc/pgx/v4"
+	pgxv5 "github.com/jackc/pgx/v5"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
 	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
 	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -47,3 +56,205 @@ func (suite *NewScanRowsErrSuite) TestOK() {
 func TestNewScanRowsErr(t *testing.T) {
 	suite.Run(t, new(NewScanRowsErrSuite))
 }
+
+// NewQueryDBErrSQLStateSuite tests NewQueryDBErr with SQLSTATE classification.
+type NewQueryDBErrSQLStateSuite struct {
+	suite.Suite
+}
+
+func (suite *NewQueryDBErrSQLStateSuite) TearDownTest() {
+	SetSQLStateRules(DefaultSQLStateRules())
+}
+
+func (suite *NewQueryDBErrSQLStateSuite) TestDefaults() {
+	tests := []struct {
+		sqlState string
+		code     meh.Code
+		message  string
+	}{
+		{sqlState: ErrCodeUniqueViolation, code: meh.ErrConflict, message: "unique violation"},
+		{sqlState: ErrCodeForeignKeyViolation, code: meh.ErrBadInput, message: "foreign key violation"},
+		{sqlState: "23502", code: meh.ErrBadInput, message: "constraint violation"},
+		{sqlState: "22001", code: meh.ErrBadInput, message: "data exception"},
+		{sqlState: "42601", code: meh.ErrInternal, message: "syntax error"},
+		{sqlState: ErrCodeSerializationFailure, code: ErrRetryable, message: "serialization failure"},
+		{sqlState: ErrCodeDeadlockDetected, code: ErrRetryable, message: "deadlock detected"},
+		{sqlState: ErrCodeQueryCanceled, code: meh.ErrCanceled, message: "query canceled"},
+		{sqlState: "08006", code: meh.ErrUnavailable, message: "connection exception"},
+		{sqlState: "53300", code: meh.ErrUnavailable, message: "insufficient resources"},
+		{sqlState: "XX000", code: meh.ErrInternal, message: ""},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.sqlState, func() {
+			for _, pgErr := range []error{&pgconn.PgError{Code: tt.sqlState}, &pgconnv5.PgError{Code: tt.sqlState}} {
+				err := NewQueryDBErr(pgErr, "query", "SELECT *").(*meh.Error)
+				suite.Equal(tt.code, meh.ErrorCode(err), "should have set correct error code")
+				suite.Equal(tt.sqlState, err.Details["sqlstate"], "should have applied sqlstate to details")
+				suite.Equal(tt.message, err.WrappedErr.(*meh.Error).Message, "should have set correct message")
+			}
+		})
+	}
+}
+
+func (suite *NewQueryDBErrSQLStateSuite) TestCustomRules() {
+	SetSQLStateRules(append(DefaultSQLStateRules(),
+		SQLStateRule{SQLState: "23514", Code: meh.ErrForbidden, Message: "check violation"}))
+	err := NewQueryDBErr(&pgconnv5.PgError{Code: "23514"}, "query", "SELECT *")
+	suite.Equal(meh.ErrForbidden, meh.ErrorCode(err), "should use exact match over prefix")
+	err = NewQueryDBErr(&pgconnv5.PgError{Code: "23502"}, "query", "SELECT *")
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should still use defaults")
+}
+
+func (suite *NewQueryDBErrSQLStateSuite) TestNoRules() {
+	SetSQLStateRules(nil)
+	err := NewQueryDBErr(&pgconnv5.PgError{Code: ErrCodeUniqueViolation}, "query", "SELECT *")
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should fall back to internal error")
+}
+
+func TestNewQueryDBErrSQLState(t *testing.T) {
+	suite.Run(t, new(NewQueryDBErrSQLStateSuite))
+}
+
+// NewQueryDBErrPgErrDetailsSuite tests extraction of details from PostgreSQL
+// errors and registered constraint messages in NewQueryDBErr.
+type NewQueryDBErrPgErrDetailsSuite struct {
+	suite.Suite
+	pgErr *pgconnv5.PgError
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) SetupTest() {
+	suite.pgErr = &pgconnv5.PgError{
+		Code:           ErrCodeUniqueViolation,
+		Message:        `duplicate key value violates unique constraint "users_email_key"`,
+		Detail:         "Key (email)=(a@b.c) already exists.",
+		SchemaName:     "public",
+		TableName:      "users",
+		ConstraintName: "users_email_key",
+	}
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TearDownTest() {
+	UnregisterConstraintMessage("users_email_key")
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TestDetails() {
+	err := NewQueryDBErr(suite.pgErr, "query", "SELECT *").(*meh.Error)
+	suite.Equal(ErrCodeUniqueViolation, err.Details[DetailKeySQLState])
+	suite.Equal("users_email_key", err.Details[DetailKeyConstraint])
+	suite.Equal("public", err.Details[DetailKeySchema])
+	suite.Equal("users", err.Details[DetailKeyTable])
+	suite.Equal(MaskedArg, err.Details[DetailKeyDetail], "should mask detail")
+	suite.NotContains(err.Details, DetailKeyColumn, "should not add empty values")
+	suite.NotContains(err.Details, DetailKeyHint, "should not add empty values")
+	suite.NotContains(err.Details, "pg_err", "should not add complete error")
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TestDetailsV4() {
+	err := NewQueryDBErr(&pgconn.PgError{
+		Code:         "22001",
+		ColumnName:   "name",
+		DataTypeName: "varchar",
+		Hint:         "shorter",
+	}, "query", "SELECT *").(*meh.Error)
+	suite.Equal("name", err.Details[DetailKeyColumn])
+	suite.Equal("varchar", err.Details[DetailKeyDataType])
+	suite.Equal(MaskedArg, err.Details[DetailKeyHint], "should mask hint")
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TestDetailKept() {
+	SetRedactionPolicy(RedactionPolicy{DefaultAction: ArgActionKeep})
+	defer SetRedactionPolicy(DefaultRedactionPolicy())
+	err := NewQueryDBErr(suite.pgErr, "query", "SELECT *").(*meh.Error)
+	suite.Equal("Key (email)=(a@b.c) already exists.", err.Details[DetailKeyDetail])
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TestRegisteredConstraint() {
+	RegisterConstraintMessage("users_email_key", "email", "email already taken")
+	err := NewQueryDBErr(suite.pgErr, "query", "SELECT *").(*meh.Error)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should return bad input")
+	suite.Equal("email already taken", err.WrappedErr.(*meh.Error).Message, "should use registered message")
+	suite.Equal("email", err.Details[DetailKeyField], "should add field to details")
+	suite.Equal([]meh.Violation{{
+		Path:       "email",
+		Constraint: "users_email_key",
+		Message:    "email already taken",
+	}}, meh.Violations(err), "should add violation for clients")
+	_, body := mehhttp.JSONResponseRenderer(httptest.NewRequest(http.MethodPost, "/", nil), err, 0)
+	suite.Contains(string(body), "email already taken", "should respond message to clients")
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TestUnregisteredConstraint() {
+	RegisterConstraintMessage("users_email_key", "email", "email already taken")
+	UnregisterConstraintMessage("users_email_key")
+	err := NewQueryDBErr(suite.pgErr, "query", "SELECT *")
+	suite.Equal(meh.ErrConflict, meh.ErrorCode(err), "should use sqlstate rules")
+}
+
+func (suite *NewQueryDBErrPgErrDetailsSuite) TestRegisteredConstraintOtherClass() {
+	RegisterConstraintMessage("users_email_key", "email", "email already taken")
+	suite.pgErr.Code = ErrCodeQueryCanceled
+	err := NewQueryDBErr(suite.pgErr, "query", "SELECT *")
+	suite.Equal(meh.ErrCanceled, meh.ErrorCode(err), "should only apply to constraint violations")
+}
+
+func TestNewQueryDBErrPgErrDetails(t *testing.T) {
+	suite.Run(t, new(NewQueryDBErrPgErrDetailsSuite))
+}
+
+// NoRowsSuite tests handling of no-rows errors.
+type NoRowsSuite struct {
+	suite.Suite
+}
+
+func (suite *NoRowsSuite) TestIsNoRows() {
+	suite.True(IsNoRows(sql.ErrNoRows), "should recognize database/sql")
+	suite.True(IsNoRows(pgxv4.ErrNoRows), "should recognize pgx v4")
+	suite.True(IsNoRows(pgxv5.ErrNoRows), "should recognize pgx v5")
+	suite.True(IsNoRows(fmt.Errorf("scan: %w", pgxv5.ErrNoRows)), "should recognize wrapped errors")
+	suite.False(IsNoRows(errors.New("no rows in result set")), "should not recognize other errors")
+	suite.False(IsNoRows(nil), "should not recognize nil")
+}
+
+func (suite *NoRowsSuite) TestQueryDBErrKeepsInternal() {
+	for _, noRowsErr := range []error{sql.ErrNoRows, pgxv4.ErrNoRows, pgxv5.ErrNoRows} {
+		suite.Equal(meh.ErrInternal, meh.ErrorCode(NewQueryDBErr(noRowsErr, "query", "SELECT *")),
+			"should return internal error")
+		suite.Equal(meh.ErrInternal, meh.ErrorCode(NewScanRowsErr(noRowsErr, "scan", "SELECT *")),
+			"should return internal error")
+	}
+}
+
+func (suite *NoRowsSuite) TestQueryAndScanRowsErrAllDrivers() {
+	for _, noRowsErr := range []error{sql.ErrNoRows, pgxv4.ErrNoRows, pgxv5.ErrNoRows} {
+		err := NewQueryAndScanRowsErr(fmt.Errorf("scan: %w", noRowsErr), "query", "SELECT *")
+		suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should return not found")
+	}
+}
+
+func (suite *NoRowsSuite) TestQueryAndScanRowsErrOtherErr() {
+	err := NewQueryAndScanRowsErr(errors.New("sad life"), "query", "SELECT *")
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should return internal error")
+}
+
+func (suite *NoRowsSuite) TestForResourceAllDrivers() {
+	for _, noRowsErr := range []error{sql.ErrNoRows, pgxv4.ErrNoRows, pgxv5.ErrNoRows} {
+		err := NewQueryAndScanRowsErrForResource(noRowsErr, "query", "user 42", "SELECT *")
+		suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should return not found")
+	}
+}
+
+func (suite *NoRowsSuite) TestForResource() {
+	err := NewQueryAndScanRowsErrForResource(pgxv5.ErrNoRows, "query user", "user 42", "SELECT *", 42).(*meh.Error)
+	suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should return not found")
+	suite.Equal("user 42", err.Details[DetailKeyResource], "should add resource to details")
+	suite.Equal("SELECT *", err.Details["query"], "should keep query in details")
+}
+
+func (suite *NoRowsSuite) TestForResourceOtherErr() {
+	err := NewQueryAndScanRowsErrForResource(errors.New("sad life"), "query user", "user 42", "SELECT *")
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should return internal error")
+}
+
+func TestNoRows(t *testing.T) {
+	suite.Run(t, new(NoRowsSuite))
+}
@@ -0,0 +1,221 @@
+package mehpg
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/lefinal/meh/mehsql"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SensitiveQueryMarker is the comment that marks queries as sensitive. Args of
+// sensitive queries are omitted from details. Use MarkSensitive in order to
+// add it to a query.
+const SensitiveQueryMarker = "/* mehpg:sensitive */"
+
+// MarkSensitive marks the given query as sensitive by prefixing it with
+// SensitiveQueryMarker. PostgreSQL ignores the comment.
+func MarkSensitive(query string) string {
+	return SensitiveQueryMarker + " " + query
+}
+
+// MaskedArg is the replacement for args with ArgActionMask.
+const MaskedArg = mehsql.MaskedArg
+
+// ArgAction describes how query args are added to details.
+type ArgAction int
+
+const (
+	// ArgActionMask replaces the arg with MaskedArg. This is the default.
+	ArgActionMask ArgAction = iota
+	// ArgActionKeep keeps the arg. Long strings and byte slices are still
+	// truncated based on RedactionPolicy.MaxStringLength and
+	// RedactionPolicy.MaxBytesLength.
+	ArgActionKeep
+	// ArgActionHash replaces the arg with a shortened SHA-256 hash of its
+	// representation. This allows correlating values without exposing them
+	// directly. However, the hash is unsalted and truncated, so values from small
+	// domains like emails, phone numbers or IDs can be recovered with a
+	// dictionary attack. Use ArgActionMask for such values.
+	ArgActionHash
+)
+
+// ArgRule decides the ArgAction for matching args.
+type ArgRule struct {
+	// Position is the 1-based position of the arg like in $1. If set, the rule
+	// applies to the arg at this position.
+	Position int
+	// NamePattern matches names of named args using pgx.NamedArgs or
+	// sql.NamedArg. If set, the rule applies to named args with matching names.
+	NamePattern *regexp.Regexp
+	// Action is the ArgAction for matching args.
+	Action ArgAction
+}
+
+// matches checks whether the ArgRule applies to the arg with the given
+// position and name. The name is empty for positional args.
+func (rule ArgRule) matches(position int, name string) bool {
+	if rule.Position != 0 && rule.Position == position {
+		return true
+	}
+	return rule.NamePattern != nil && name != "" && rule.NamePattern.MatchString(name)
+}
+
+// RedactionPolicy describes how query args as well as the detail and hint of
+// PostgreSQL errors are added to details in NewQueryDBErr and Wrap.
+type RedactionPolicy struct {
+	// Rules are checked in order and the first matching one decides the ArgAction.
+	Rules []ArgRule
+	// DefaultAction is the ArgAction for args not matching any rule. It is also
+	// used for DetailKeyDetail and DetailKeyHint, as PostgreSQL includes values
+	// in them like in "Key (email)=(a@b.c) already exists.".
+	DefaultAction ArgAction
+	// MaxStringLength is the maximum number of characters of kept strings. Longer
+	// ones are truncated. If not set, strings are not truncated.
+	MaxStringLength int
+	// MaxBytesLength is the maximum number of kept bytes of byte slices. Longer
+	// ones are truncated and hex-encoded. If not set, byte slices are not
+	// truncated.
+	MaxBytesLength int
+	// IsSensitiveQuery decides whether args should be omitted entirely for the
+	// given query. Queries marked via MarkSensitive are always sensitive.
+	IsSensitiveQuery func(query string) bool
+}
+
+// DefaultRedactionPolicy returns the default RedactionPolicy, which is safe for
+// production logs as all args are masked.
+func DefaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{
+		DefaultAction:   ArgActionMask,
+		MaxStringLength: 256,
+		MaxBytesLength:  64,
+	}
+}
+
+var (
+	// redactionPolicy is the RedactionPolicy used for query args in details.
+	redactionPolicy = DefaultRedactionPolicy()
+	// redactionPolicyMutex locks redactionPolicy.
+	redactionPolicyMutex sync.RWMutex
+)
+
+// SetRedactionPolicy sets the RedactionPolicy for query args in details. Per
+// default, DefaultRedactionPolicy is used.
+func SetRedactionPolicy(policy RedactionPolicy) {
+	redactionPolicyMutex.Lock()
+	defer redactionPolicyMutex.Unlock()
+	redactionPolicy = policy
+}
+
+// RedactArgs redacts the given args for the given query using the
+// RedactionPolicy set via SetRedactionPolicy. If the query is sensitive, false
+// is returned. It is a mehsql.ArgsRedactor and can be set via
+// mehsql.SetArgsRedactor in order to use the same policy for mehsql.
+func RedactArgs(query string, args []any) ([]any, bool) {
+	redactionPolicyMutex.RLock()
+	policy := redactionPolicy
+	redactionPolicyMutex.RUnlock()
+	if strings.Contains(query, SensitiveQueryMarker) ||
+		(policy.IsSensitiveQuery != nil && policy.IsSensitiveQuery(query)) {
+		return nil, false
+	}
+	if args == nil {
+		return nil, true
+	}
+	redacted := make([]any, 0, len(args))
+	for i, arg := range args {
+		redacted = append(redacted, policy.redactArg(i+1, arg))
+	}
+	return redacted, true
+}
+
+// redactPgMessage redacts the given detail or hint of a PostgreSQL error using
+// RedactionPolicy.DefaultAction of the RedactionPolicy set via
+// SetRedactionPolicy.
+func redactPgMessage(message string) any {
+	redactionPolicyMutex.RLock()
+	policy := redactionPolicy
+	redactionPolicyMutex.RUnlock()
+	return policy.apply(policy.DefaultAction, message)
+}
+
+// redactArg redacts the given arg at the given 1-based position.
+func (policy RedactionPolicy) redactArg(position int, arg any) any {
+	switch arg := arg.(type) {
+	case pgx.NamedArgs:
+		redacted := make(pgx.NamedArgs, len(arg))
+		for name, value := range arg {
+			redacted[name] = policy.apply(policy.action(0, name), value)
+		}
+		return redacted
+	case sql.NamedArg:
+		arg.Value = policy.apply(policy.action(position, arg.Name), arg.Value)
+		return arg
+	default:
+		return policy.apply(policy.action(position, ""), arg)
+	}
+}
+
+// action returns the ArgAction for the arg with the given position and name.
+func (policy RedactionPolicy) action(position int, name string) ArgAction {
+	for _, rule := range policy.Rules {
+		if rule.matches(position, name) {
+			return rule.Action
+		}
+	}
+	return policy.DefaultAction
+}
+
+// apply applies the given ArgAction to the given value.
+func (policy RedactionPolicy) apply(action ArgAction, value any) any {
+	switch action {
+	case ArgActionKeep:
+		return policy.truncate(value)
+	case ArgActionHash:
+		return hashArg(value)
+	default:
+		return MaskedArg
+	}
+}
+
+// truncate truncates long strings and byte slices based on
+// RedactionPolicy.MaxStringLength and RedactionPolicy.MaxBytesLength.
+func (policy RedactionPolicy) truncate(value any) any {
+	switch value := value.(type) {
+	case string:
+		if policy.MaxStringLength <= 0 || utf8.RuneCountInString(value) <= policy.MaxStringLength {
+			return value
+		}
+		runes := []rune(value)
+		return fmt.Sprintf("%s... (%d more characters)", string(runes[:policy.MaxStringLength]),
+			len(runes)-policy.MaxStringLength)
+	case []byte:
+		if policy.MaxBytesLength <= 0 || len(value) <= policy.MaxBytesLength {
+			return value
+		}
+		return fmt.Sprintf("%s... (%d bytes)", hex.EncodeToString(value[:policy.MaxBytesLength]), len(value))
+	default:
+		return value
+	}
+}
+
+// hashArg returns the first 8 bytes of the hex-encoded SHA-256 hash of the
+// given value's representation.
+func hashArg(value any) string {
+	var b []byte
+	switch value := value.(type) {
+	case []byte:
+		b = value
+	case string:
+		b = []byte(value)
+	default:
+		b = []byte(fmt.Sprintf("%v", value))
+	}
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
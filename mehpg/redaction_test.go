@@ -0,0 +1,93 @@
+package mehpg
+
+import (
+	"database/sql"
+	"github.com/jackc/pgx/v5"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// RedactionPolicySuite tests RedactionPolicy in NewQueryDBErr.
+type RedactionPolicySuite struct {
+	suite.Suite
+}
+
+func (suite *RedactionPolicySuite) TearDownTest() {
+	SetRedactionPolicy(DefaultRedactionPolicy())
+}
+
+// args returns the args from details of NewQueryDBErr.
+func (suite *RedactionPolicySuite) args(query string, args ...any) (any, bool) {
+	err := NewQueryDBErr(sql.ErrConnDone, "query", query, args...).(*meh.Error)
+	redacted, ok := err.Details[DetailKeyArgs]
+	return redacted, ok
+}
+
+func (suite *RedactionPolicySuite) TestDefaultMasks() {
+	args, ok := suite.args("SELECT $1, $2", "secret", 42)
+	suite.Require().True(ok, "should add args")
+	suite.Equal([]any{MaskedArg, MaskedArg}, args, "should mask all args")
+}
+
+func (suite *RedactionPolicySuite) TestByPosition() {
+	SetRedactionPolicy(RedactionPolicy{
+		Rules: []ArgRule{
+			{Position: 1, Action: ArgActionKeep},
+			{Position: 2, Action: ArgActionHash},
+		},
+	})
+	args, _ := suite.args("SELECT $1, $2, $3", "hello", "secret", "world")
+	suite.Equal([]any{"hello", hashArg("secret"), MaskedArg}, args)
+	suite.True(strings.HasPrefix(hashArg("secret"), "sha256:"), "should prefix hash")
+	suite.NotEqual(hashArg("secret"), hashArg("other"), "should hash values")
+}
+
+func (suite *RedactionPolicySuite) TestByName() {
+	SetRedactionPolicy(RedactionPolicy{
+		Rules: []ArgRule{
+			{NamePattern: regexp.MustCompile(`(?i)password|token`), Action: ArgActionMask},
+		},
+		DefaultAction: ArgActionKeep,
+	})
+	args, _ := suite.args("SELECT @name, @password", pgx.NamedArgs{"name": "hello", "password": "secret"})
+	suite.Equal([]any{pgx.NamedArgs{"name": "hello", "password": MaskedArg}}, args, "should redact pgx named args")
+	args, _ = suite.args("SELECT @token", sql.Named("token", "secret"), sql.Named("name", "hello"))
+	suite.Equal([]any{sql.Named("token", MaskedArg), sql.Named("name", "hello")}, args,
+		"should redact sql named args")
+}
+
+func (suite *RedactionPolicySuite) TestTruncate() {
+	SetRedactionPolicy(RedactionPolicy{
+		DefaultAction:   ArgActionKeep,
+		MaxStringLength: 3,
+		MaxBytesLength:  2,
+	})
+	args, _ := suite.args("SELECT $1, $2, $3", "hällo", []byte{1, 2, 3, 4}, "hi")
+	suite.Equal([]any{"häl... (2 more characters)", "0102... (4 bytes)", "hi"}, args)
+}
+
+func (suite *RedactionPolicySuite) TestSensitiveMarker() {
+	SetRedactionPolicy(RedactionPolicy{DefaultAction: ArgActionKeep})
+	_, ok := suite.args(MarkSensitive("SELECT $1"), "secret")
+	suite.False(ok, "should omit args for sensitive queries")
+}
+
+func (suite *RedactionPolicySuite) TestIsSensitiveQuery() {
+	SetRedactionPolicy(RedactionPolicy{
+		DefaultAction: ArgActionKeep,
+		IsSensitiveQuery: func(query string) bool {
+			return strings.Contains(query, "credentials")
+		},
+	})
+	_, ok := suite.args("SELECT * FROM credentials WHERE id = $1", 1)
+	suite.False(ok, "should omit args for sensitive queries")
+	_, ok = suite.args("SELECT * FROM users WHERE id = $1", 1)
+	suite.True(ok, "should add args for other queries")
+}
+
+func TestRedactionPolicy(t *testing.T) {
+	suite.Run(t, new(RedactionPolicySuite))
+}
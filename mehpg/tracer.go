@@ -0,0 +1,272 @@
+package mehpg
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/lefinal/meh"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Keys for details added by Wrap for queries that failed while being traced
+// by Tracer.
+const (
+	DetailKeyDuration = "pg_duration"
+	DetailKeyHost     = "pg_host"
+	DetailKeyDatabase = "pg_database"
+	DetailKeyUser     = "pg_user"
+)
+
+// DefaultTracerCapacity is the default for TracerOptions.Capacity.
+const DefaultTracerCapacity = 1024
+
+// TracerOptions for NewTracer.
+type TracerOptions struct {
+	// Capacity is the maximum number of failed queries that are remembered for
+	// lookup in Tracer.Wrap. If exceeded, the oldest ones are discarded. If not set,
+	// DefaultTracerCapacity is used.
+	Capacity int
+}
+
+var (
+	_ pgx.QueryTracer = (*Tracer)(nil)
+	_ pgx.BatchTracer = (*Tracer)(nil)
+)
+
+// Tracer implements pgx.QueryTracer and pgx.BatchTracer for pgx v5. It
+// captures the SQL, args, duration and connection info of failed queries, so
+// that repositories do not need to pass them manually. As tracers cannot
+// replace the error returned from pgx, the information is remembered for the
+// returned error and applied in Tracer.Wrap.
+//
+// Set the Tracer in pgx.ConnConfig and keep it for wrapping errors:
+//
+//	tracer := mehpg.NewTracer(mehpg.TracerOptions{})
+//	config.Tracer = tracer
+type Tracer struct {
+	// capacity is the maximum number of entries in failures.
+	capacity int
+	// failures holds the information regarding failed queries by the returned
+	// error. Only errors with pointer types are used as keys, so that keys are
+	// compared by identity and hashing never panics.
+	failures map[error]queryFailure
+	// order holds the errors from failures in insertion order for discarding the
+	// oldest ones.
+	order []error
+	// m locks failures and order.
+	m sync.Mutex
+}
+
+// queryFailure holds information regarding a failed query that was captured by
+// Tracer.
+type queryFailure struct {
+	sql string
+	// args are the args redacted with RedactArgs.
+	args []any
+	// argsOmitted is true if RedactArgs omitted the args for a sensitive query.
+	argsOmitted bool
+	duration    time.Duration
+	host        string
+	database    string
+	user        string
+}
+
+// details returns the meh.Details for the queryFailure.
+func (failure queryFailure) details() meh.Details {
+	details := meh.Details{DetailKeyQuery: failure.sql}
+	if !failure.argsOmitted {
+		details[DetailKeyArgs] = failure.args
+	}
+	details[DetailKeyDuration] = failure.duration.String()
+	for k, v := range map[string]string{
+		DetailKeyHost:     failure.host,
+		DetailKeyDatabase: failure.database,
+		DetailKeyUser:     failure.user,
+	} {
+		if v != "" {
+			details[k] = v
+		}
+	}
+	return details
+}
+
+// NewTracer creates a new Tracer. Use Tracer.Wrap for wrapping errors from
+// connections using the Tracer.
+func NewTracer(options TracerOptions) *Tracer {
+	if options.Capacity <= 0 {
+		options.Capacity = DefaultTracerCapacity
+	}
+	tracer := &Tracer{
+		capacity: options.Capacity,
+		failures: make(map[error]queryFailure),
+		order:    make([]error, 0, options.Capacity),
+	}
+	return tracer
+}
+
+// tracerStartKey is the context key for the start time of a traced query or
+// batch.
+type tracerStartKey struct{}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (tracer *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, tracerStartKey{}, tracedQueryStart{
+		start: time.Now(),
+		sql:   data.SQL,
+		args:  data.Args,
+	})
+}
+
+// tracedQueryStart is the context value for tracerStartKey.
+type tracedQueryStart struct {
+	start time.Time
+	sql   string
+	args  []any
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (tracer *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if data.Err == nil {
+		return
+	}
+	start, _ := ctx.Value(tracerStartKey{}).(tracedQueryStart)
+	tracer.record(conn, data.Err, start.start, start.sql, start.args)
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (tracer *Tracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	return context.WithValue(ctx, tracerStartKey{}, tracedQueryStart{start: time.Now()})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.
+func (tracer *Tracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if data.Err == nil {
+		return
+	}
+	start, _ := ctx.Value(tracerStartKey{}).(tracedQueryStart)
+	tracer.record(conn, data.Err, start.start, data.SQL, data.Args)
+}
+
+// TraceBatchEnd implements pgx.BatchTracer. Failures are already captured in
+// TraceBatchQuery.
+func (tracer *Tracer) TraceBatchEnd(_ context.Context, _ *pgx.Conn, _ pgx.TraceBatchEndData) {
+}
+
+// record remembers the given query information for the given error. Args are
+// redacted with RedactArgs before being stored. Sentinel errors and errors
+// without pointer types are ignored.
+func (tracer *Tracer) record(conn *pgx.Conn, err error, start time.Time, sql string, args []any) {
+	if !isTraceableErr(err) {
+		return
+	}
+	redactedArgs, ok := RedactArgs(sql, args)
+	failure := queryFailure{
+		sql:         sql,
+		args:        redactedArgs,
+		argsOmitted: !ok,
+	}
+	if !start.IsZero() {
+		failure.duration = time.Since(start)
+	}
+	if conn != nil {
+		config := conn.Config()
+		failure.host = config.Host
+		if config.Port != 0 {
+			failure.host += ":" + strconv.Itoa(int(config.Port))
+		}
+		failure.database = config.Database
+		failure.user = config.User
+	}
+	tracer.m.Lock()
+	defer tracer.m.Unlock()
+	if _, ok := tracer.failures[err]; !ok {
+		if len(tracer.order) >= tracer.capacity {
+			delete(tracer.failures, tracer.order[0])
+			tracer.order = tracer.order[1:]
+		}
+		tracer.order = append(tracer.order, err)
+	}
+	tracer.failures[err] = failure
+}
+
+// lookup returns the queryFailure for the given error or any error in its
+// chain. Both Unwrap() error and Unwrap() []error as well as meh.Error levels
+// are followed.
+func (tracer *Tracer) lookup(err error) (queryFailure, bool) {
+	tracer.m.Lock()
+	defer tracer.m.Unlock()
+	return tracer.lookupLocked(err)
+}
+
+// lookupLocked implements lookup while the lock is held.
+func (tracer *Tracer) lookupLocked(err error) (queryFailure, bool) {
+	if err == nil {
+		return queryFailure{}, false
+	}
+	if isPointerErr(err) {
+		if failure, ok := tracer.failures[err]; ok {
+			return failure, true
+		}
+	}
+	switch err := err.(type) {
+	case *meh.Error:
+		return tracer.lookupLocked(err.WrappedErr)
+	case interface{ Unwrap() []error }:
+		for _, wrapped := range err.Unwrap() {
+			if failure, ok := tracer.lookupLocked(wrapped); ok {
+				return failure, true
+			}
+		}
+	case interface{ Unwrap() error }:
+		return tracer.lookupLocked(err.Unwrap())
+	}
+	return queryFailure{}, false
+}
+
+// isTraceableErr checks whether the given error is unique for a failed query.
+// Sentinel errors like pgx.ErrNoRows or context.Canceled are shared between
+// queries and therefore not traceable. Errors without pointer types are not
+// traceable either as they cannot be identified.
+func isTraceableErr(err error) bool {
+	if !isPointerErr(err) {
+		return false
+	}
+	return err != context.Canceled && err != context.DeadlineExceeded && !IsNoRows(err)
+}
+
+// isPointerErr checks whether the given error has a pointer type. Such errors
+// are compared by identity and can safely be used as map keys.
+func isPointerErr(err error) bool {
+	return reflect.TypeOf(err).Kind() == reflect.Pointer
+}
+
+// Wrap creates a new meh.Error for the given error like NewQueryDBErr. If the
+// error was returned from a query traced by the Tracer, the SQL, args, duration
+// and connection info are added to details with the DetailKey-constants.
+// Otherwise, only the error is classified. This allows repositories to only
+// wrap errors instead of passing queries manually. Wrap can be called on a nil
+// Tracer, which only classifies the error.
+func (tracer *Tracer) Wrap(err error, message string, details meh.Details) error {
+	if err == nil {
+		return nil
+	}
+	wrapDetails := make(meh.Details)
+	if tracer != nil {
+		if failure, ok := tracer.lookup(err); ok {
+			wrapDetails = failure.details()
+		}
+	}
+	for k, v := range details {
+		wrapDetails[k] = v
+	}
+	return newQueryDBErr(err, message, wrapDetails, false)
+}
+
+// Wrap creates a new meh.Error for the given error like NewQueryDBErr without
+// looking up captured query information. Use Tracer.Wrap for errors from
+// connections that use a Tracer.
+func Wrap(err error, message string, details meh.Details) error {
+	return (*Tracer)(nil).Wrap(err, message, details)
+}
@@ -0,0 +1,137 @@
+package mehpg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+// TracerSuite tests Tracer and Wrap.
+type TracerSuite struct {
+	suite.Suite
+	tracer *Tracer
+}
+
+func (suite *TracerSuite) SetupTest() {
+	suite.tracer = NewTracer(TracerOptions{Capacity: 2})
+}
+
+// failQuery simulates a failed query with the given error.
+func (suite *TracerSuite) failQuery(err error, sql string, args ...any) {
+	ctx := suite.tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  sql,
+		Args: args,
+	})
+	suite.tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: err})
+}
+
+func (suite *TracerSuite) TestQuery() {
+	pgErr := &pgconnv5.PgError{Code: ErrCodeUniqueViolation}
+	suite.failQuery(pgErr, "INSERT INTO users", "hello")
+	err := suite.tracer.Wrap(fmt.Errorf("exec: %w", pgErr), "create user", meh.Details{"user": "hello"}).(*meh.Error)
+	suite.Equal(meh.ErrConflict, meh.ErrorCode(err), "should classify error")
+	suite.Equal("INSERT INTO users", err.Details[DetailKeyQuery], "should add query")
+	suite.Equal([]any{MaskedArg}, err.Details[DetailKeyArgs], "should add redacted args")
+	suite.Contains(err.Details, DetailKeyDuration, "should add duration")
+	suite.Equal("hello", err.Details["user"], "should keep passed details")
+}
+
+func (suite *TracerSuite) TestQuerySuccess() {
+	ctx := suite.tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	suite.tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	suite.Empty(suite.tracer.failures, "should not record successful queries")
+}
+
+func (suite *TracerSuite) TestBatch() {
+	pgErr := &pgconnv5.PgError{Code: "22001"}
+	ctx := suite.tracer.TraceBatchStart(context.Background(), nil, pgx.TraceBatchStartData{})
+	suite.tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "SELECT 1"})
+	suite.tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "SELECT 2", Args: []any{2}, Err: pgErr})
+	suite.tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{Err: pgErr})
+	err := suite.tracer.Wrap(pgErr, "batch", nil).(*meh.Error)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should classify error")
+	suite.Equal("SELECT 2", err.Details[DetailKeyQuery], "should add query of failed batch query")
+}
+
+func (suite *TracerSuite) TestUntraced() {
+	err := suite.tracer.Wrap(errors.New("sad life"), "query", nil).(*meh.Error)
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should classify error")
+	suite.NotContains(err.Details, DetailKeyQuery, "should not add query")
+}
+
+func (suite *TracerSuite) TestNil() {
+	suite.Nil(suite.tracer.Wrap(nil, "query", nil))
+}
+
+func (suite *TracerSuite) TestSentinel() {
+	suite.failQuery(pgx.ErrNoRows, "SELECT 1")
+	suite.failQuery(context.Canceled, "SELECT 1")
+	suite.Empty(suite.tracer.failures, "should not record sentinel errors")
+	err := suite.tracer.Wrap(pgx.ErrNoRows, "query", nil)
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should classify error like NewQueryDBErr")
+}
+
+func (suite *TracerSuite) TestCapacity() {
+	errs := []error{errors.New("1"), errors.New("2"), errors.New("3")}
+	for i, err := range errs {
+		suite.failQuery(err, fmt.Sprintf("SELECT %d", i))
+	}
+	suite.Len(suite.tracer.failures, 2, "should discard oldest")
+	_, ok := suite.tracer.lookup(errs[0])
+	suite.False(ok, "should have discarded oldest")
+	_, ok = suite.tracer.lookup(errs[2])
+	suite.True(ok, "should keep newest")
+}
+
+func (suite *TracerSuite) TestStoresRedactedArgs() {
+	err := errors.New("sad life")
+	suite.failQuery(err, "SELECT $1", "secret")
+	failure, ok := suite.tracer.lookup(err)
+	suite.Require().True(ok, "should record failure")
+	suite.Equal([]any{MaskedArg}, failure.args, "should store redacted args")
+}
+
+func (suite *TracerSuite) TestJoined() {
+	pgErr := &pgconnv5.PgError{Code: ErrCodeUniqueViolation}
+	suite.failQuery(pgErr, "INSERT INTO users")
+	err := suite.tracer.Wrap(meh.Wrap(errors.Join(errors.New("other"), pgErr), "wrap", nil), "create user", nil).(*meh.Error)
+	suite.Equal("INSERT INTO users", err.Details[DetailKeyQuery], "should follow joined errors")
+}
+
+func (suite *TracerSuite) TestNonPointerErr() {
+	// A struct error holding an uncomparable value would panic when used as map
+	// key.
+	err := uncomparableErr{value: []int{1}}
+	suite.NotPanics(func() {
+		suite.failQuery(err, "SELECT 1")
+		_ = suite.tracer.Wrap(err, "query", nil)
+	})
+	suite.Empty(suite.tracer.failures, "should not record non-pointer errors")
+}
+
+func (suite *TracerSuite) TestPackageWrapIgnoresTracers() {
+	pgErr := &pgconnv5.PgError{Code: ErrCodeUniqueViolation}
+	suite.failQuery(pgErr, "INSERT INTO users")
+	err := Wrap(pgErr, "create user", nil).(*meh.Error)
+	suite.Equal(meh.ErrConflict, meh.ErrorCode(err), "should classify error")
+	suite.NotContains(err.Details, DetailKeyQuery, "should not add query")
+}
+
+// uncomparableErr is an error with a non-pointer type whose interface field
+// holds an uncomparable value.
+type uncomparableErr struct {
+	value any
+}
+
+func (e uncomparableErr) Error() string {
+	return "uncomparable"
+}
+
+func TestTracer(t *testing.T) {
+	suite.Run(t, new(TracerSuite))
+}
@@ -0,0 +1,231 @@
+package mehpg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/lefinal/meh"
+	"math/rand"
+	"time"
+)
+
+// Keys for details added to errors returned from RunTx and RunPgxTx.
+const (
+	// DetailKeyTxAttempts holds the number of attempts for running the
+	// transaction.
+	DetailKeyTxAttempts = "tx_attempts"
+	// DetailKeyRollbackErr holds the *meh.Error for the error that occurred while
+	// rolling back the transaction after the original error. As a *meh.Error, it
+	// keeps code and details and marshals to JSON as well as to zap objects.
+	DetailKeyRollbackErr = "rollback_err"
+)
+
+// DefaultTxMaxAttempts is the default for TxOptions.MaxAttempts.
+const DefaultTxMaxAttempts = 3
+
+// DefaultTxBackoff is the default for TxOptions.Backoff. It waits 10ms before
+// the second attempt and doubles the duration for each further attempt, up to
+// one second. A random jitter of up to half the duration is added, so that
+// conflicting transactions do not retry in lockstep.
+func DefaultTxBackoff(attempt int) time.Duration {
+	backoff := time.Second
+	if attempt < 8 {
+		backoff = 10 * time.Millisecond << (attempt - 1)
+	}
+	return backoff + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// NoTxBackoff can be used as TxOptions.Backoff for retrying immediately.
+func NoTxBackoff(_ int) time.Duration {
+	return 0
+}
+
+// TxOptions are options for RunTx.
+type TxOptions struct {
+	// MaxAttempts is the maximum number of attempts for running the transaction.
+	// Transactions are retried if they fail with ErrRetryable, which is the case
+	// for serialization failures and deadlocks. If not set,
+	// DefaultTxMaxAttempts is used. Set it to 1 in order to disable retries.
+	MaxAttempts int
+	// Backoff returns the duration to wait before retrying after the given
+	// number of failed attempts, starting at 1. Waiting is aborted when the
+	// context is done. If not set, DefaultTxBackoff is used.
+	Backoff func(attempt int) time.Duration
+	// TxOptions are passed to sql.DB.BeginTx.
+	TxOptions *sql.TxOptions
+}
+
+// PgxTxOptions are options for RunPgxTx.
+type PgxTxOptions struct {
+	// MaxAttempts is the same as TxOptions.MaxAttempts.
+	MaxAttempts int
+	// Backoff is the same as TxOptions.Backoff.
+	Backoff func(attempt int) time.Duration
+	// TxOptions are passed to pgx.Conn.BeginTx.
+	TxOptions pgx.TxOptions
+}
+
+// SQLTxBeginner begins transactions for database/sql. It is implemented by
+// sql.DB and sql.Conn.
+type SQLTxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// PgxTxBeginner begins transactions for pgx v5. It is implemented by pgx.Conn
+// and pgxpool.Pool.
+type PgxTxBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// RunTx runs the given function in a transaction for database/sql. The
+// transaction is committed if the function returns without error. Otherwise,
+// or if the function panics, the transaction is rolled back. If rollback fails,
+// the rollback error is added to details of the returned error with key
+// DetailKeyRollbackErr. Rollback errors for transactions that the function
+// already closed, like sql.ErrTxDone and pgx.ErrTxClosed, are ignored. If the function or commit fails with ErrRetryable,
+// the whole function is retried up to TxOptions.MaxAttempts, waiting for
+// TxOptions.Backoff in between. The number of attempts is added to details of
+// returned errors with key DetailKeyTxAttempts.
+func RunTx(ctx context.Context, db SQLTxBeginner, options TxOptions, fn TxFunc[*sql.Tx]) error {
+	return runTx(ctx, txRetry{maxAttempts: options.MaxAttempts, backoff: options.Backoff}, txFuncs[*sql.Tx]{
+		begin: func(ctx context.Context) (*sql.Tx, error) {
+			return db.BeginTx(ctx, options.TxOptions)
+		},
+		commit: func(_ context.Context, tx *sql.Tx) error {
+			return tx.Commit()
+		},
+		rollback: func(_ context.Context, tx *sql.Tx) error {
+			return tx.Rollback()
+		},
+	}, fn)
+}
+
+// RunPgxTx is the same as RunTx but for pgx v5.
+func RunPgxTx(ctx context.Context, db PgxTxBeginner, options PgxTxOptions, fn TxFunc[pgx.Tx]) error {
+	return runTx(ctx, txRetry{maxAttempts: options.MaxAttempts, backoff: options.Backoff}, txFuncs[pgx.Tx]{
+		begin: func(ctx context.Context) (pgx.Tx, error) {
+			return db.BeginTx(ctx, options.TxOptions)
+		},
+		commit: func(ctx context.Context, tx pgx.Tx) error {
+			return tx.Commit(ctx)
+		},
+		rollback: func(ctx context.Context, tx pgx.Tx) error {
+			return tx.Rollback(ctx)
+		},
+	}, fn)
+}
+
+// TxFunc is the function that is run in a transaction by RunTx and RunPgxTx.
+type TxFunc[T any] func(ctx context.Context, tx T) error
+
+// txRetry holds the retry options for runTx.
+type txRetry struct {
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
+}
+
+// txFuncs are the driver-specific functions for runTx.
+type txFuncs[T any] struct {
+	begin    func(ctx context.Context) (T, error)
+	commit   func(ctx context.Context, tx T) error
+	rollback func(ctx context.Context, tx T) error
+}
+
+// runTx implements RunTx and RunPgxTx.
+func runTx[T any](ctx context.Context, retry txRetry, funcs txFuncs[T], fn TxFunc[T]) error {
+	maxAttempts := retry.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultTxMaxAttempts
+	}
+	backoff := retry.backoff
+	if backoff == nil {
+		backoff = DefaultTxBackoff
+	}
+	var err error
+	attempt := 0
+	for attempt < maxAttempts {
+		attempt++
+		err = runTxAttempt(ctx, funcs, fn)
+		if err == nil {
+			return nil
+		}
+		if !IsRetryable(err) || attempt == maxAttempts || !waitTxBackoff(ctx, backoff(attempt)) {
+			break
+		}
+	}
+	return meh.ApplyDetails(err, meh.Details{DetailKeyTxAttempts: attempt})
+}
+
+// waitTxBackoff waits for the given duration. It returns false if the context
+// is done before.
+func waitTxBackoff(ctx context.Context, d time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// runTxAttempt runs a single attempt in runTx.
+func runTxAttempt[T any](ctx context.Context, funcs txFuncs[T], fn TxFunc[T]) (err error) {
+	tx, err := funcs.begin(ctx)
+	if err != nil {
+		return Wrap(err, "begin tx", nil)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			// Rollback error is not of interest as we panic anyway.
+			_ = funcs.rollback(context.WithoutCancel(ctx), tx)
+			panic(r)
+		}
+	}()
+	err = fn(ctx, tx)
+	if err != nil {
+		rollbackErr := funcs.rollback(context.WithoutCancel(ctx), tx)
+		if rollbackErr != nil && !isTxClosedErr(rollbackErr) {
+			// Store it as *meh.Error as foreign errors without exported fields marshal
+			// to {}.
+			rollbackErr = Wrap(rollbackErr, "rollback tx", nil)
+			return meh.ApplyDetails(err, meh.Details{DetailKeyRollbackErr: meh.Cast(rollbackErr)})
+		}
+		return err
+	}
+	err = funcs.commit(ctx, tx)
+	if err != nil {
+		return Wrap(err, "commit tx", nil)
+	}
+	return nil
+}
+
+// isTxClosedErr checks whether the given error is returned when rolling back a
+// transaction that was already committed or rolled back.
+func isTxClosedErr(err error) bool {
+	return errors.Is(err, sql.ErrTxDone) || errors.Is(err, pgx.ErrTxClosed)
+}
+
+// IsRetryable checks whether the given error has the meh.Code ErrRetryable or
+// wraps a PostgreSQL error that is classified with ErrRetryable, like
+// serialization failures or deadlocks.
+func IsRetryable(err error) bool {
+	for it := meh.NewErrorUnwrapper(err); it.Next(); {
+		if e, ok := it.Current().(*meh.Error); ok {
+			if e.Code == ErrRetryable {
+				return true
+			}
+			continue
+		}
+		classification, ok := Classifier.Classify(it.Current())
+		return ok && classification.Code == ErrRetryable
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package mehpg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+	"testing"
+	"time"
+)
+
+// RunTxSuite tests RunTx using an in-memory SQLite database.
+type RunTxSuite struct {
+	suite.Suite
+	db *sql.DB
+}
+
+func (suite *RunTxSuite) SetupTest() {
+	var err error
+	suite.db, err = sql.Open("sqlite", "file::memory:")
+	suite.Require().NoError(err, "open should not fail")
+	suite.db.SetMaxOpenConns(1)
+	_, err = suite.db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY)`)
+	suite.Require().NoError(err, "setup should not fail")
+}
+
+func (suite *RunTxSuite) TearDownTest() {
+	_ = suite.db.Close()
+}
+
+// userCount returns the number of users in the database.
+func (suite *RunTxSuite) userCount() int {
+	var count int
+	suite.Require().NoError(suite.db.QueryRow(`SELECT count(*) FROM users`).Scan(&count))
+	return count
+}
+
+func (suite *RunTxSuite) TestCommit() {
+	err := RunTx(context.Background(), suite.db, TxOptions{}, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO users DEFAULT VALUES`)
+		return err
+	})
+	suite.NoError(err, "should not fail")
+	suite.Equal(1, suite.userCount(), "should have committed")
+}
+
+func (suite *RunTxSuite) TestRollbackOnErr() {
+	fnErr := meh.NewBadInputErr("sad life", nil)
+	err := RunTx(context.Background(), suite.db, TxOptions{}, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO users DEFAULT VALUES`)
+		suite.Require().NoError(err)
+		return fnErr
+	})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should keep original error")
+	suite.Equal(1, err.(*meh.Error).Details[DetailKeyTxAttempts], "should add attempts")
+	suite.Equal(0, suite.userCount(), "should have rolled back")
+}
+
+func (suite *RunTxSuite) TestRollbackOnPanic() {
+	suite.Panics(func() {
+		_ = RunTx(context.Background(), suite.db, TxOptions{}, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO users DEFAULT VALUES`)
+			suite.Require().NoError(err)
+			panic("sad life")
+		})
+	})
+	suite.Equal(0, suite.userCount(), "should have rolled back")
+}
+
+func (suite *RunTxSuite) TestRollbackAfterClose() {
+	fnErr := meh.NewBadInputErr("sad life", nil)
+	err := RunTx(context.Background(), suite.db, TxOptions{}, func(_ context.Context, tx *sql.Tx) error {
+		suite.Require().NoError(tx.Rollback())
+		return fnErr
+	})
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should keep original error")
+	suite.NotContains(meh.ToMap(err), "1/"+DetailKeyRollbackErr, "should ignore closed tx")
+}
+
+func (suite *RunTxSuite) TestRetry() {
+	attempts := 0
+	err := RunTx(context.Background(), suite.db, TxOptions{}, func(ctx context.Context, tx *sql.Tx) error {
+		attempts++
+		_, err := tx.ExecContext(ctx, `INSERT INTO users DEFAULT VALUES`)
+		suite.Require().NoError(err)
+		if attempts < 2 {
+			return &pgconnv5.PgError{Code: ErrCodeSerializationFailure}
+		}
+		return nil
+	})
+	suite.NoError(err, "should succeed after retry")
+	suite.Equal(2, attempts, "should have retried")
+	suite.Equal(1, suite.userCount(), "should only commit last attempt")
+}
+
+func (suite *RunTxSuite) TestRetryLimit() {
+	attempts := 0
+	options := TxOptions{MaxAttempts: 2}
+	err := RunTx(context.Background(), suite.db, options, func(_ context.Context, _ *sql.Tx) error {
+		attempts++
+		return Wrap(&pgconnv5.PgError{Code: ErrCodeDeadlockDetected}, "update", nil)
+	})
+	suite.Equal(ErrRetryable, meh.ErrorCode(err), "should return retryable error")
+	suite.Equal(2, attempts, "should stop at max attempts")
+	suite.Equal(2, err.(*meh.Error).Details[DetailKeyTxAttempts], "should add attempts")
+}
+
+func (suite *RunTxSuite) TestBackoff() {
+	var backoffs []int
+	options := TxOptions{
+		MaxAttempts: 3,
+		Backoff: func(attempt int) time.Duration {
+			backoffs = append(backoffs, attempt)
+			return time.Millisecond
+		},
+	}
+	err := RunTx(context.Background(), suite.db, options, func(_ context.Context, _ *sql.Tx) error {
+		return &pgconnv5.PgError{Code: ErrCodeSerializationFailure}
+	})
+	suite.Error(err, "should fail")
+	suite.Equal([]int{1, 2}, backoffs, "should only back off between attempts")
+}
+
+func (suite *RunTxSuite) TestBackoffCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	options := TxOptions{
+		Backoff: func(_ int) time.Duration {
+			cancel()
+			return time.Hour
+		},
+	}
+	attempts := 0
+	err := RunTx(ctx, suite.db, options, func(_ context.Context, _ *sql.Tx) error {
+		attempts++
+		return &pgconnv5.PgError{Code: ErrCodeSerializationFailure}
+	})
+	suite.True(IsRetryable(err), "should return last error")
+	suite.Equal(1, attempts, "should stop waiting when context is done")
+}
+
+func (suite *RunTxSuite) TestBeginErr() {
+	_ = suite.db.Close()
+	err := RunTx(context.Background(), suite.db, TxOptions{}, func(_ context.Context, _ *sql.Tx) error {
+		suite.Fail("should not be called")
+		return nil
+	})
+	suite.Error(err, "should fail")
+}
+
+func TestRunTx(t *testing.T) {
+	suite.Run(t, new(RunTxSuite))
+}
+
+// TestDefaultTxBackoff assures that DefaultTxBackoff grows exponentially with
+// jitter and is capped.
+func TestDefaultTxBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		min     time.Duration
+	}{
+		{attempt: 1, min: 10 * time.Millisecond},
+		{attempt: 2, min: 20 * time.Millisecond},
+		{attempt: 4, min: 80 * time.Millisecond},
+		{attempt: 8, min: time.Second},
+		{attempt: 100, min: time.Second},
+	}
+	for _, tt := range tests {
+		backoff := DefaultTxBackoff(tt.attempt)
+		if backoff < tt.min || backoff > tt.min*3/2 {
+			t.Errorf("attempt %d: backoff %v should be between %v and %v", tt.attempt, backoff, tt.min, tt.min*3/2)
+		}
+	}
+}
+
+// pgxTxStub is a pgx.Tx that records commit and rollback calls.
+type pgxTxStub struct {
+	pgx.Tx
+	committed   bool
+	rolledBack  bool
+	commitErr   error
+	rollbackErr error
+}
+
+func (tx *pgxTxStub) Commit(_ context.Context) error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *pgxTxStub) Rollback(_ context.Context) error {
+	tx.rolledBack = true
+	return tx.rollbackErr
+}
+
+// pgxTxBeginnerStub is a PgxTxBeginner that returns the given transactions in
+// order.
+type pgxTxBeginnerStub struct {
+	txs []*pgxTxStub
+}
+
+func (db *pgxTxBeginnerStub) BeginTx(_ context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	tx := &pgxTxStub{}
+	db.txs = append(db.txs, tx)
+	return tx, nil
+}
+
+// RunPgxTxSuite tests RunPgxTx.
+type RunPgxTxSuite struct {
+	suite.Suite
+	db *pgxTxBeginnerStub
+}
+
+func (suite *RunPgxTxSuite) SetupTest() {
+	suite.db = &pgxTxBeginnerStub{}
+}
+
+func (suite *RunPgxTxSuite) TestCommit() {
+	err := RunPgxTx(context.Background(), suite.db, PgxTxOptions{}, func(_ context.Context, _ pgx.Tx) error {
+		return nil
+	})
+	suite.NoError(err, "should not fail")
+	suite.Require().Len(suite.db.txs, 1)
+	suite.True(suite.db.txs[0].committed, "should have committed")
+	suite.False(suite.db.txs[0].rolledBack, "should not have rolled back")
+}
+
+func (suite *RunPgxTxSuite) TestRollbackErr() {
+	err := RunPgxTx(context.Background(), suite.db, PgxTxOptions{}, func(_ context.Context, tx pgx.Tx) error {
+		tx.(*pgxTxStub).rollbackErr = errors.New("connection lost")
+		return meh.NewNotFoundErr("sad life", nil)
+	})
+	suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should keep original error")
+	suite.True(suite.db.txs[0].rolledBack, "should have rolled back")
+	rollbackErr, ok := meh.ToMap(err)["1/"+DetailKeyRollbackErr].(*meh.Error)
+	suite.Require().True(ok, "should add rollback error to details")
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(rollbackErr), "should keep code")
+	found := false
+	for it := meh.NewErrorUnwrapper(rollbackErr); it.Next(); {
+		found = found || it.Current() == suite.db.txs[0].rollbackErr
+	}
+	suite.True(found, "should wrap original rollback error")
+	raw, marshalErr := json.Marshal(meh.ToMap(err))
+	suite.Require().NoError(marshalErr, "marshal should not fail")
+	suite.Contains(string(raw), "connection lost", "should marshal rollback error")
+}
+
+func (suite *RunPgxTxSuite) TestRollbackAfterClose() {
+	err := RunPgxTx(context.Background(), suite.db, PgxTxOptions{}, func(_ context.Context, tx pgx.Tx) error {
+		tx.(*pgxTxStub).rollbackErr = pgx.ErrTxClosed
+		return meh.NewNotFoundErr("sad life", nil)
+	})
+	suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should keep original error")
+	suite.NotContains(meh.ToMap(err), "1/"+DetailKeyRollbackErr, "should ignore closed tx")
+}
+
+func (suite *RunPgxTxSuite) TestRetryOnCommit() {
+	options := PgxTxOptions{MaxAttempts: 5, Backoff: NoTxBackoff}
+	err := RunPgxTx(context.Background(), suite.db, options, func(_ context.Context, tx pgx.Tx) error {
+		if len(suite.db.txs) < 3 {
+			tx.(*pgxTxStub).commitErr = &pgconnv5.PgError{Code: ErrCodeSerializationFailure}
+		}
+		return nil
+	})
+	suite.NoError(err, "should succeed after retries")
+	suite.Len(suite.db.txs, 3, "should have retried")
+}
+
+func (suite *RunPgxTxSuite) TestNoRetryForOtherErrs() {
+	err := RunPgxTx(context.Background(), suite.db, PgxTxOptions{}, func(_ context.Context, _ pgx.Tx) error {
+		return &pgconnv5.PgError{Code: ErrCodeUniqueViolation}
+	})
+	suite.Error(err, "should fail")
+	suite.Len(suite.db.txs, 1, "should not retry")
+}
+
+func TestRunPgxTx(t *testing.T) {
+	suite.Run(t, new(RunPgxTxSuite))
+}
@@ -0,0 +1,216 @@
+package mehsentry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/pkg/errors"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Level is the severity of an Event.
+type Level string
+
+// Levels supported by Sentry.
+const (
+	LevelDebug   Level = "debug"
+	LevelInfo    Level = "info"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+	LevelFatal   Level = "fatal"
+)
+
+// TagCode is the tag key for the meh.Code of the reported error as returned by
+// meh.ErrorCode.
+const TagCode = "meh.code"
+
+// Event is a Sentry event as described in the Sentry event payload
+// documentation.
+//
+// See: https://develop.sentry.dev/sdk/event-payloads/.
+type Event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Platform    string                 `json:"platform"`
+	Level       Level                  `json:"level"`
+	Logger      string                 `json:"logger,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	Exception   *ExceptionList         `json:"exception,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Fingerprint []string               `json:"fingerprint,omitempty"`
+}
+
+// ExceptionList holds the exceptions of an Event.
+type ExceptionList struct {
+	// Values are the exceptions, ordered from the innermost (root cause) to the
+	// outermost one as expected by Sentry.
+	Values []Exception `json:"values"`
+}
+
+// Exception is a single level of the reported error.
+type Exception struct {
+	// Type is the meh.Code for meh.Error or the type of foreign errors.
+	Type string `json:"type"`
+	// Value is the message of the level.
+	Value string `json:"value"`
+	// Stacktrace is set for levels with meh.Error.Trace.
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace holds the frames of an Exception.
+type Stacktrace struct {
+	// Frames are ordered from the oldest call to the newest one as expected by
+	// Sentry.
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is a single stack frame in Stacktrace.
+type Frame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// LevelTranslator translates the given meh.Code to the Level of an Event.
+type LevelTranslator func(code meh.Code) Level
+
+// Options are used for creating an Event with NewEvent.
+type Options struct {
+	// Logger is the optional name of the logger.
+	Logger string
+	// ServerName is the optional name of the host that reports the event.
+	ServerName string
+	// Release is the optional release version of the application.
+	Release string
+	// Environment is the optional environment like production or staging.
+	Environment string
+	// LevelTranslator is used for choosing the Level of events. If not set, every
+	// event has LevelError.
+	LevelTranslator LevelTranslator
+}
+
+// NewEvent creates an Event from the given error. Each level of the error
+// becomes an Exception and stack traces are taken from meh.Error.Trace. The
+// effective meh.Code is set as tag with key TagCode, details as returned by
+// meh.ToMap are added as extra context and the fingerprint is set to
+// meh.Fingerprint. Details that cannot be marshalled as JSON are added in their
+// formatted representation. Frames are marked as in-app using
+// meh.IsInAppPackage.
+func NewEvent(err error, options Options) *Event {
+	code := meh.ErrorCode(err)
+	level := LevelError
+	if options.LevelTranslator != nil {
+		level = options.LevelTranslator(code)
+	}
+	extra := meh.ToMap(err)
+	delete(extra, meh.MapFieldErrorCode)
+	delete(extra, meh.MapFieldErrorMessage)
+	for k, v := range extra {
+		// A single value that cannot be marshalled must not fail the whole event.
+		if _, err := json.Marshal(v); err != nil {
+			extra[k] = fmt.Sprintf("%+v", v)
+		}
+	}
+	return &Event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Platform:    "go",
+		Level:       level,
+		Logger:      options.Logger,
+		ServerName:  options.ServerName,
+		Release:     options.Release,
+		Environment: options.Environment,
+		Message:     meh.Cast(err).Error(),
+		Exception:   &ExceptionList{Values: exceptions(err)},
+		Tags:        map[string]string{TagCode: string(code)},
+		Extra:       extra,
+		Fingerprint: []string{meh.Fingerprint(err)},
+	}
+}
+
+// newEventID generates a random event id in the format expected by Sentry.
+func newEventID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// exceptions builds the Exception list for the wrap chain of the given error,
+// beginning with the innermost one. Levels with meh.ErrNeutral and without
+// message or stack trace, like the ones from meh.ApplyDetails, are skipped.
+func exceptions(err error) []Exception {
+	values := make([]Exception, 0)
+	for it := meh.NewErrorUnwrapper(err); it.Next(); {
+		var exception Exception
+		if e, ok := it.Current().(*meh.Error); ok {
+			if e.Code == meh.ErrNeutral && e.Message == "" && e.Trace.StackTrace == nil {
+				continue
+			}
+			exception = Exception{
+				Type:       string(e.Code),
+				Value:      e.Message,
+				Stacktrace: stacktrace(e.Trace.StackTrace),
+			}
+			if exception.Type == "" {
+				exception.Type = "unexpected"
+			}
+		} else {
+			exception = Exception{
+				Type:  fmt.Sprintf("%T", it.Current()),
+				Value: it.Current().Error(),
+			}
+		}
+		values = append(values, exception)
+	}
+	// Sentry expects the innermost exception first.
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	return values
+}
+
+// stacktrace converts the given errors.StackTrace to Stacktrace. If the trace
+// is empty, nil is returned.
+func stacktrace(trace errors.StackTrace) *Stacktrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	frames := make([]Frame, 0, len(trace))
+	// Sentry expects the oldest frame first while errors.StackTrace starts with
+	// the newest one.
+	for i := len(trace) - 1; i >= 0; i-- {
+		pc := uintptr(trace[i]) - 1
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			continue
+		}
+		file, line := fn.FileLine(pc)
+		module := meh.FuncPackage(fn.Name())
+		function := strings.TrimPrefix(fn.Name()[len(module):], ".")
+		frames = append(frames, Frame{
+			Function: function,
+			Module:   module,
+			Filename: fileName(file),
+			AbsPath:  file,
+			Lineno:   line,
+			InApp:    meh.IsInAppPackage(module),
+		})
+	}
+	return &Stacktrace{Frames: frames}
+}
+
+// fileName returns the last element of the given file path.
+func fileName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
@@ -0,0 +1,35 @@
+// Package mehsentry provides conversion of meh.Error to Sentry events and
+// reporting them to Sentry.
+package mehsentry
+
+import (
+	"context"
+	"github.com/lefinal/meh"
+)
+
+// Exporter converts errors to events using NewEvent and sends them via a
+// Transport.
+type Exporter struct {
+	transport Transport
+	options   Options
+}
+
+// NewExporter creates a new Exporter that sends events via the given Transport.
+// The Options are used for each created Event.
+func NewExporter(transport Transport, options Options) *Exporter {
+	return &Exporter{
+		transport: transport,
+		options:   options,
+	}
+}
+
+// Export converts the given error to an Event and sends it. The id of the sent
+// Event is returned.
+func (ex *Exporter) Export(ctx context.Context, err error) (string, error) {
+	event := NewEvent(err, ex.options)
+	sendErr := ex.transport.Send(ctx, event)
+	if sendErr != nil {
+		return "", meh.Wrap(sendErr, "send event", meh.Details{"event_id": event.EventID})
+	}
+	return event.EventID, nil
+}
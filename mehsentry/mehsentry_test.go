@@ -0,0 +1,214 @@
+package mehsentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// NewEventSuite tests NewEvent.
+type NewEventSuite struct {
+	suite.Suite
+	err error
+}
+
+func (suite *NewEventSuite) SetupTest() {
+	suite.err = meh.Wrap(meh.ApplyStackTrace(meh.NewNotFoundErrFromErr(errors.New("sad life"), "get user",
+		meh.Details{"user_id": 42})), "handle request", meh.Details{"method": "GET"})
+}
+
+func (suite *NewEventSuite) TestExceptions() {
+	event := NewEvent(suite.err, Options{})
+	suite.Require().NotNil(event.Exception)
+	values := event.Exception.Values
+	suite.Require().Len(values, 3, "should include each level")
+	suite.Equal("*errors.errorString", values[0].Type, "should start with root cause")
+	suite.Equal("sad life", values[0].Value)
+	suite.Equal(string(meh.ErrNotFound), values[1].Type)
+	suite.Equal("get user", values[1].Value)
+	suite.Require().NotNil(values[1].Stacktrace, "should set stack trace")
+	suite.NotEmpty(values[1].Stacktrace.Frames)
+	suite.Equal(string(meh.ErrNeutral), values[2].Type)
+	suite.Equal("handle request", values[2].Value)
+	suite.Nil(values[2].Stacktrace, "should not set stack trace for levels without one")
+}
+
+func (suite *NewEventSuite) TestFrameOrder() {
+	event := NewEvent(suite.err, Options{})
+	frames := event.Exception.Values[1].Stacktrace.Frames
+	last := frames[len(frames)-1]
+	suite.Equal("github.com/lefinal/meh", last.Module, "should end with newest frame")
+	suite.False(last.InApp, "should not mark meh frames as in-app")
+}
+
+func (suite *NewEventSuite) TestInAppFrames() {
+	event := NewEvent(suite.err, Options{})
+	for _, frame := range event.Exception.Values[1].Stacktrace.Frames {
+		if frame.Module == "github.com/lefinal/meh/mehsentry" {
+			suite.True(frame.InApp, "should mark frames of main module as in-app")
+			suite.Contains(frame.Function, "NewEventSuite", "should set function without package")
+			return
+		}
+	}
+	suite.Fail("should contain frame of test")
+}
+
+func (suite *NewEventSuite) TestUnmarshallableDetail() {
+	event := NewEvent(meh.NewInternalErr("sad life", meh.Details{"fn": func() {}, "ok": 1}), Options{})
+	_, err := json.Marshal(event)
+	suite.NoError(err, "should marshal event")
+	suite.IsType("", event.Extra["0/fn"], "should format detail")
+	suite.Equal(1, event.Extra["0/ok"], "should keep other details")
+}
+
+func (suite *NewEventSuite) TestSkipDetailLevels() {
+	event := NewEvent(meh.ApplyDetails(suite.err, meh.Details{"a": "b"}), Options{})
+	suite.Len(event.Exception.Values, 3, "should skip levels only holding details")
+	suite.Equal("b", event.Extra["0/a"], "should keep details")
+}
+
+func (suite *NewEventSuite) TestTagsAndExtra() {
+	event := NewEvent(suite.err, Options{})
+	suite.Equal(string(meh.ErrNotFound), event.Tags[TagCode], "should set code tag")
+	suite.Equal(42, event.Extra["1/user_id"], "should set details as extra")
+	suite.Equal("GET", event.Extra["0/method"], "should set details as extra")
+	suite.NotContains(event.Extra, meh.MapFieldErrorCode, "should not duplicate code")
+	suite.Equal(suite.err.Error(), event.Message, "should set message")
+}
+
+func (suite *NewEventSuite) TestFingerprint() {
+	event := NewEvent(suite.err, Options{})
+	suite.Equal([]string{meh.Fingerprint(suite.err)}, event.Fingerprint)
+}
+
+func (suite *NewEventSuite) TestOptions() {
+	event := NewEvent(suite.err, Options{
+		Logger:      "app",
+		ServerName:  "host",
+		Release:     "1.0.0",
+		Environment: "production",
+		LevelTranslator: func(code meh.Code) Level {
+			if code == meh.ErrNotFound {
+				return LevelInfo
+			}
+			return LevelError
+		},
+	})
+	suite.Equal(LevelInfo, event.Level)
+	suite.Equal("app", event.Logger)
+	suite.Equal("host", event.ServerName)
+	suite.Equal("1.0.0", event.Release)
+	suite.Equal("production", event.Environment)
+	suite.Len(event.EventID, 32, "should set event id")
+}
+
+func (suite *NewEventSuite) TestDefaultLevel() {
+	suite.Equal(LevelError, NewEvent(suite.err, Options{}).Level)
+}
+
+func TestNewEvent(t *testing.T) {
+	suite.Run(t, new(NewEventSuite))
+}
+
+// TestExporter_Export tests Exporter.Export with WriterTransport.
+func TestExporter_Export(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := NewExporter(NewWriterTransport(&buf), Options{Environment: "test"})
+	eventID, err := exporter.Export(context.Background(), meh.NewInternalErr("sad life", nil))
+	require.NoError(t, err, "export should not fail")
+	var event Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event), "should write valid json")
+	assert.Equal(t, eventID, event.EventID)
+	assert.Equal(t, "test", event.Environment)
+	assert.Equal(t, string(meh.ErrInternal), event.Tags[TagCode])
+}
+
+// NewHTTPTransportSuite tests NewHTTPTransport.
+type NewHTTPTransportSuite struct {
+	suite.Suite
+}
+
+func (suite *NewHTTPTransportSuite) TestOK() {
+	transport, err := NewHTTPTransport("https://key@sentry.example.com/prefix/42", nil)
+	suite.Require().NoError(err)
+	suite.Equal("https://sentry.example.com/prefix/api/42/envelope/", transport.endpoint)
+	suite.Equal("key", transport.publicKey)
+}
+
+func (suite *NewHTTPTransportSuite) TestMissingKey() {
+	_, err := NewHTTPTransport("https://sentry.example.com/42", nil)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err))
+}
+
+func (suite *NewHTTPTransportSuite) TestMissingProject() {
+	_, err := NewHTTPTransport("https://key@sentry.example.com/", nil)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err))
+}
+
+func (suite *NewHTTPTransportSuite) TestUnsupportedScheme() {
+	_, err := NewHTTPTransport("ftp://key@sentry.example.com/42", nil)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err))
+}
+
+func TestNewHTTPTransport(t *testing.T) {
+	suite.Run(t, new(NewHTTPTransportSuite))
+}
+
+// HTTPTransportSendSuite tests HTTPTransport.Send.
+type HTTPTransportSendSuite struct {
+	suite.Suite
+	status   int
+	req      *http.Request
+	body     []byte
+	srv      *httptest.Server
+	exporter *Exporter
+}
+
+func (suite *HTTPTransportSendSuite) SetupTest() {
+	suite.status = http.StatusOK
+	suite.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.req = r
+		suite.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(suite.status)
+	}))
+	transport, err := NewHTTPTransport(strings.Replace(suite.srv.URL, "http://", "http://key@", 1)+"/42", suite.srv.Client())
+	suite.Require().NoError(err, "create transport should not fail")
+	suite.exporter = NewExporter(transport, Options{})
+}
+
+func (suite *HTTPTransportSendSuite) TearDownTest() {
+	suite.srv.Close()
+}
+
+func (suite *HTTPTransportSendSuite) TestOK() {
+	eventID, err := suite.exporter.Export(context.Background(), meh.NewInternalErr("sad life", nil))
+	suite.Require().NoError(err, "export should not fail")
+	suite.Require().NotNil(suite.req, "should have sent request")
+	suite.Equal("/api/42/envelope/", suite.req.URL.Path)
+	suite.Contains(suite.req.Header.Get("X-Sentry-Auth"), "sentry_key=key")
+	lines := strings.Split(strings.TrimSpace(string(suite.body)), "\n")
+	suite.Require().Len(lines, 3, "should send envelope with header, item header and event")
+	var event Event
+	suite.Require().NoError(json.Unmarshal([]byte(lines[2]), &event))
+	suite.Equal(eventID, event.EventID)
+}
+
+func (suite *HTTPTransportSendSuite) TestErrorStatus() {
+	suite.status = http.StatusTooManyRequests
+	_, err := suite.exporter.Export(context.Background(), meh.NewInternalErr("sad life", nil))
+	suite.Error(err, "should fail")
+}
+
+func TestHTTPTransport_Send(t *testing.T) {
+	suite.Run(t, new(HTTPTransportSendSuite))
+}
@@ -0,0 +1,162 @@
+package mehsentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport sends events to Sentry.
+type Transport interface {
+	// Send the given Event.
+	Send(ctx context.Context, event *Event) error
+}
+
+// WriterTransport is a Transport that writes each Event as single JSON line to
+// an io.Writer. This is mainly useful for testing and debugging.
+type WriterTransport struct {
+	w      io.Writer
+	wMutex sync.Mutex
+}
+
+// NewWriterTransport creates a new WriterTransport that writes to the given
+// io.Writer.
+func NewWriterTransport(w io.Writer) *WriterTransport {
+	return &WriterTransport{w: w}
+}
+
+// Send writes the given Event as JSON line.
+func (t *WriterTransport) Send(_ context.Context, event *Event) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return meh.NewInternalErrFromErr(err, "marshal event", nil)
+	}
+	t.wMutex.Lock()
+	defer t.wMutex.Unlock()
+	_, err = t.w.Write(append(eventJSON, '\n'))
+	if err != nil {
+		return meh.NewInternalErrFromErr(err, "write event", nil)
+	}
+	return nil
+}
+
+// clientName is the name that is reported to Sentry as client.
+const clientName = "mehsentry/1.0"
+
+// HTTPTransport is a Transport that sends events to the envelope endpoint of
+// the project described by a Sentry DSN.
+type HTTPTransport struct {
+	client    *http.Client
+	endpoint  string
+	publicKey string
+	dsn       string
+}
+
+// NewHTTPTransport creates a new HTTPTransport for the given DSN in the format
+// {PROTOCOL}://{PUBLIC_KEY}@{HOST}{PATH}/{PROJECT_ID}. If the http.Client is
+// nil, http.DefaultClient is used.
+func NewHTTPTransport(dsn string, client *http.Client) (*HTTPTransport, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, meh.NewBadInputErrFromErr(err, "parse dsn", nil)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, meh.NewBadInputErr("unsupported dsn scheme", meh.Details{"scheme": parsed.Scheme})
+	}
+	if parsed.User == nil || parsed.User.Username() == "" {
+		return nil, meh.NewBadInputErr("missing public key in dsn", nil)
+	}
+	lastSlash := strings.LastIndex(parsed.Path, "/")
+	projectID := parsed.Path[lastSlash+1:]
+	if projectID == "" {
+		return nil, meh.NewBadInputErr("missing project id in dsn", nil)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPTransport{
+		client:    client,
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/envelope/", parsed.Scheme, parsed.Host, parsed.Path[:lastSlash], projectID),
+		publicKey: parsed.User.Username(),
+		dsn:       dsn,
+	}, nil
+}
+
+// Send the given Event as envelope to Sentry.
+func (t *HTTPTransport) Send(ctx context.Context, event *Event) error {
+	envelope, err := t.envelope(event)
+	if err != nil {
+		return meh.Wrap(err, "build envelope", nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return meh.NewInternalErrFromErr(err, "create request", meh.Details{"endpoint": t.endpoint})
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s",
+		clientName, t.publicKey))
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &meh.Error{
+			Code:       mehhttp.ErrServiceNotReachable,
+			WrappedErr: err,
+			Message:    "do request",
+			Details:    meh.Details{"endpoint": t.endpoint},
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &meh.Error{
+			Code:    mehhttp.ErrServiceNotReachable,
+			Message: "unexpected response status",
+			Details: meh.Details{
+				"endpoint": t.endpoint,
+				"status":   resp.StatusCode,
+			},
+		}
+	}
+	return nil
+}
+
+// envelope builds the envelope for the given Event.
+//
+// See: https://develop.sentry.dev/sdk/envelopes/.
+func (t *HTTPTransport) envelope(event *Event) ([]byte, error) {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return nil, meh.NewInternalErrFromErr(err, "marshal event", nil)
+	}
+	headerJSON, err := json.Marshal(map[string]interface{}{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+		"dsn":      t.dsn,
+	})
+	if err != nil {
+		return nil, meh.NewInternalErrFromErr(err, "marshal envelope header", nil)
+	}
+	itemHeaderJSON, err := json.Marshal(map[string]interface{}{
+		"type":   "event",
+		"length": len(eventJSON),
+	})
+	if err != nil {
+		return nil, meh.NewInternalErrFromErr(err, "marshal item header", nil)
+	}
+	var envelope bytes.Buffer
+	envelope.Write(headerJSON)
+	envelope.WriteByte('\n')
+	envelope.Write(itemHeaderJSON)
+	envelope.WriteByte('\n')
+	envelope.Write(eventJSON)
+	envelope.WriteByte('\n')
+	return envelope.Bytes(), nil
+}
@@ -0,0 +1,184 @@
+// Package mehsql provides driver-agnostic error functionality for database/sql
+// using pluggable classifiers.
+package mehsql
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lefinal/meh"
+	"sort"
+	"sync"
+)
+
+// Keys for details added in NewQueryErr.
+const (
+	DetailKeyQuery = "query"
+	DetailKeyArgs  = "args"
+)
+
+// MaskedArg is the replacement for args using the default ArgsRedactor.
+const MaskedArg = meh.RedactedValue
+
+// Classification is the result of classifying a driver-specific error with a
+// Classifier.
+type Classification struct {
+	// Code is the meh.Code for the error.
+	Code meh.Code
+	// Message is the message for the error level with the Code.
+	Message string
+	// Details are added to the details of the created error. This is used for
+	// extracted information like constraint names.
+	Details meh.Details
+}
+
+// Classifier classifies errors returned from database drivers.
+type Classifier interface {
+	// Classify returns the Classification for the given error. If the error is
+	// unknown to the Classifier, false is returned.
+	Classify(err error) (Classification, bool)
+}
+
+// ClassifierFunc is a function implementing Classifier.
+type ClassifierFunc func(err error) (Classification, bool)
+
+// Classify calls the ClassifierFunc.
+func (f ClassifierFunc) Classify(err error) (Classification, bool) {
+	return f(err)
+}
+
+var (
+	// classifiers are the registered classifiers.
+	classifiers = make(map[int]Classifier)
+	// nextClassifierID is the id for the next classifier that is added to
+	// classifiers. The id also describes the order of registration.
+	nextClassifierID = 0
+	// classifiersMutex locks classifiers and nextClassifierID.
+	classifiersMutex sync.RWMutex
+)
+
+// RegisterClassifier registers the given Classifier to be used in Classify.
+// Classifiers are asked in order of registration. The returned function
+// removes the classifier again.
+func RegisterClassifier(classifier Classifier) func() {
+	classifiersMutex.Lock()
+	defer classifiersMutex.Unlock()
+	id := nextClassifierID
+	nextClassifierID++
+	classifiers[id] = classifier
+	return func() {
+		classifiersMutex.Lock()
+		defer classifiersMutex.Unlock()
+		delete(classifiers, id)
+	}
+}
+
+// Classify classifies the given error using the classifiers registered via
+// RegisterClassifier. If no classifier knows the error, DefaultClassifier is
+// used.
+func Classify(err error) (Classification, bool) {
+	classifiersMutex.RLock()
+	ids := make([]int, 0, len(classifiers))
+	for id := range classifiers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	ordered := make([]Classifier, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, classifiers[id])
+	}
+	classifiersMutex.RUnlock()
+	for _, classifier := range ordered {
+		if classification, ok := classifier.Classify(err); ok {
+			return classification, true
+		}
+	}
+	return DefaultClassifier.Classify(err)
+}
+
+// DefaultClassifier classifies errors from database/sql. sql.ErrNoRows results
+// in meh.ErrNotFound while sql.ErrTxDone and sql.ErrConnDone result in
+// meh.ErrInternal.
+var DefaultClassifier Classifier = ClassifierFunc(func(err error) (Classification, bool) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return Classification{Code: meh.ErrNotFound, Message: "no rows"}, true
+	case errors.Is(err, sql.ErrTxDone):
+		return Classification{Code: meh.ErrInternal, Message: "tx done"}, true
+	case errors.Is(err, sql.ErrConnDone):
+		return Classification{Code: meh.ErrInternal, Message: "connection done"}, true
+	default:
+		return Classification{}, false
+	}
+})
+
+// ArgsRedactor redacts the given args of the given query before they are added
+// to details. If args should be omitted, false is returned.
+type ArgsRedactor func(query string, args []any) ([]any, bool)
+
+var (
+	// argsRedactor is the ArgsRedactor used in NewQueryErr.
+	argsRedactor ArgsRedactor = MaskArgs
+	// argsRedactorMutex locks argsRedactor.
+	argsRedactorMutex sync.RWMutex
+)
+
+// SetArgsRedactor sets the ArgsRedactor to use in NewQueryErr. Per default,
+// MaskArgs is used.
+func SetArgsRedactor(redactor ArgsRedactor) {
+	argsRedactorMutex.Lock()
+	defer argsRedactorMutex.Unlock()
+	argsRedactor = redactor
+}
+
+// MaskArgs is an ArgsRedactor that replaces all args with MaskedArg in order
+// to be safe for production logs.
+func MaskArgs(_ string, args []any) ([]any, bool) {
+	if args == nil {
+		return nil, true
+	}
+	masked := make([]any, 0, len(args))
+	for range args {
+		masked = append(masked, MaskedArg)
+	}
+	return masked, true
+}
+
+// NewQueryErr creates a new meh.Error for the given error that was returned
+// for the given query. The meh.Code is determined using Classify. If the error
+// is unknown, meh.ErrInternal is used. The query and args, redacted using the
+// ArgsRedactor set via SetArgsRedactor, are added to details.
+func NewQueryErr(err error, message string, query string, args ...any) error {
+	details := meh.Details{DetailKeyQuery: query}
+	argsRedactorMutex.RLock()
+	redactor := argsRedactor
+	argsRedactorMutex.RUnlock()
+	if redactedArgs, ok := redactor(query, args); ok {
+		details[DetailKeyArgs] = redactedArgs
+	}
+	classification, ok := Classify(err)
+	return NewErr(err, message, classification, ok, details)
+}
+
+// NewErr creates a new meh.Error for the given error with the given
+// Classification and details. If classified is false or the Classification
+// has meh.ErrNeutral, meh.ErrInternal is used. This allows packages with own
+// classifiers, like mehpg, to create the same error structure as NewQueryErr.
+// The given details are not altered.
+func NewErr(err error, message string, classification Classification, classified bool, details meh.Details) error {
+	if !classified || classification.Code == meh.ErrNeutral {
+		return meh.NewInternalErrFromErr(err, message, details)
+	}
+	merged := make(meh.Details, len(details)+len(classification.Details))
+	for k, v := range details {
+		merged[k] = v
+	}
+	for k, v := range classification.Details {
+		merged[k] = v
+	}
+	details = merged
+	return meh.Wrap(&meh.Error{
+		Code:       classification.Code,
+		Message:    classification.Message,
+		WrappedErr: err,
+	}, message, details)
+}
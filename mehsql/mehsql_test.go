@@ -0,0 +1,122 @@
+package mehsql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+// errSadLife is used as custom driver error in tests.
+var errSadLife = errors.New("sad life")
+
+// ClassifySuite tests Classify and RegisterClassifier.
+type ClassifySuite struct {
+	suite.Suite
+}
+
+func (suite *ClassifySuite) TestDefault() {
+	tests := []struct {
+		err  error
+		code meh.Code
+	}{
+		{err: sql.ErrNoRows, code: meh.ErrNotFound},
+		{err: fmt.Errorf("scan: %w", sql.ErrNoRows), code: meh.ErrNotFound},
+		{err: sql.ErrTxDone, code: meh.ErrInternal},
+		{err: sql.ErrConnDone, code: meh.ErrInternal},
+	}
+	for _, tt := range tests {
+		classification, ok := Classify(tt.err)
+		suite.True(ok, "should classify %v", tt.err)
+		suite.Equal(tt.code, classification.Code, "should classify %v correctly", tt.err)
+	}
+	_, ok := Classify(errSadLife)
+	suite.False(ok, "should not classify unknown errors")
+}
+
+func (suite *ClassifySuite) TestRegister() {
+	removeFirst := RegisterClassifier(ClassifierFunc(func(err error) (Classification, bool) {
+		if errors.Is(err, errSadLife) {
+			return Classification{Code: meh.ErrConflict}, true
+		}
+		return Classification{}, false
+	}))
+	removeSecond := RegisterClassifier(ClassifierFunc(func(_ error) (Classification, bool) {
+		return Classification{Code: meh.ErrForbidden}, true
+	}))
+	classification, _ := Classify(errSadLife)
+	suite.Equal(meh.ErrConflict, classification.Code, "should ask classifiers in order of registration")
+	classification, _ = Classify(sql.ErrNoRows)
+	suite.Equal(meh.ErrForbidden, classification.Code, "should prefer registered classifiers")
+	removeSecond()
+	classification, _ = Classify(sql.ErrNoRows)
+	suite.Equal(meh.ErrNotFound, classification.Code, "should have removed classifier")
+	removeFirst()
+	_, ok := Classify(errSadLife)
+	suite.False(ok, "should have removed classifier")
+}
+
+func TestClassify(t *testing.T) {
+	suite.Run(t, new(ClassifySuite))
+}
+
+// NewQueryErrSuite tests NewQueryErr.
+type NewQueryErrSuite struct {
+	suite.Suite
+}
+
+func (suite *NewQueryErrSuite) TearDownTest() {
+	SetArgsRedactor(MaskArgs)
+}
+
+func (suite *NewQueryErrSuite) TestClassified() {
+	err := NewQueryErr(sql.ErrNoRows, "query user", "SELECT *", 42).(*meh.Error)
+	suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should classify error")
+	suite.Equal("query user", err.Message, "should apply message")
+	suite.Equal("SELECT *", err.Details[DetailKeyQuery], "should add query to details")
+	suite.Equal([]any{MaskedArg}, err.Details[DetailKeyArgs], "should mask args per default")
+	suite.Equal(sql.ErrNoRows, err.WrappedErr.(*meh.Error).WrappedErr, "should wrap original error")
+}
+
+func (suite *NewQueryErrSuite) TestUnclassified() {
+	err := NewQueryErr(errSadLife, "query", "SELECT *").(*meh.Error)
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should fall back to internal error")
+	suite.Equal(errSadLife, err.WrappedErr, "should wrap original error")
+}
+
+func (suite *NewQueryErrSuite) TestArgsRedactor() {
+	SetArgsRedactor(func(_ string, args []any) ([]any, bool) {
+		return args, true
+	})
+	err := NewQueryErr(errSadLife, "query", "SELECT *", 42).(*meh.Error)
+	suite.Equal([]any{42}, err.Details[DetailKeyArgs], "should use args redactor")
+	SetArgsRedactor(func(_ string, _ []any) ([]any, bool) {
+		return nil, false
+	})
+	err = NewQueryErr(errSadLife, "query", "SELECT *", 42).(*meh.Error)
+	suite.NotContains(err.Details, DetailKeyArgs, "should omit args")
+}
+
+func (suite *NewQueryErrSuite) TestClassificationDetails() {
+	details := meh.Details{"query": "SELECT *"}
+	err := NewErr(errSadLife, "query", Classification{
+		Code:    meh.ErrBadInput,
+		Message: "constraint violation",
+		Details: meh.Details{"constraint": "users_pkey"},
+	}, true, details).(*meh.Error)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should use classified code")
+	suite.Equal("users_pkey", err.Details["constraint"], "should add classification details")
+	suite.Equal("SELECT *", err.Details["query"], "should keep passed details")
+	suite.Equal(meh.Details{"query": "SELECT *"}, details, "should not alter passed details")
+}
+
+func (suite *NewQueryErrSuite) TestNeutralClassification() {
+	err := NewErr(errSadLife, "query", Classification{Code: meh.ErrNeutral}, true, nil)
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err), "should fall back to internal error")
+}
+
+func TestNewQueryErr(t *testing.T) {
+	suite.Run(t, new(NewQueryErrSuite))
+}
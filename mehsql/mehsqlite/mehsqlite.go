@@ -0,0 +1,118 @@
+// Package mehsqlite provides a mehsql.Classifier for SQLite errors from
+// modernc.org/sqlite.
+package mehsqlite
+
+import (
+	"errors"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehsql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"sync"
+)
+
+// Keys for details extracted from SQLite errors.
+const (
+	DetailKeyCode     = "sqlite_code"
+	DetailKeyCodeName = "sqlite_code_name"
+)
+
+// Rule maps an SQLite result code to a meh.Code and message.
+type Rule struct {
+	// ResultCode is either an extended result code like
+	// sqlite3.SQLITE_CONSTRAINT_UNIQUE or a primary one like
+	// sqlite3.SQLITE_CONSTRAINT.
+	ResultCode int
+	// Code is the meh.Code to use for matching errors.
+	Code meh.Code
+	// Message is the message for the error level with the Code.
+	Message string
+}
+
+// DefaultRules returns the default rules used in Classifier.
+func DefaultRules() []Rule {
+	return []Rule{
+		{ResultCode: sqlite3.SQLITE_CONSTRAINT_UNIQUE, Code: meh.ErrConflict, Message: "unique violation"},
+		{ResultCode: sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, Code: meh.ErrConflict, Message: "unique violation"},
+		{ResultCode: sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY, Code: meh.ErrBadInput, Message: "foreign key violation"},
+		{ResultCode: sqlite3.SQLITE_CONSTRAINT, Code: meh.ErrBadInput, Message: "constraint violation"},
+		{ResultCode: sqlite3.SQLITE_MISMATCH, Code: meh.ErrBadInput, Message: "data exception"},
+		{ResultCode: sqlite3.SQLITE_TOOBIG, Code: meh.ErrBadInput, Message: "data exception"},
+		{ResultCode: sqlite3.SQLITE_RANGE, Code: meh.ErrInternal, Message: "parameter out of range"},
+		{ResultCode: sqlite3.SQLITE_INTERRUPT, Code: meh.ErrCanceled, Message: "query interrupted"},
+		{ResultCode: sqlite3.SQLITE_BUSY, Code: meh.ErrUnavailable, Message: "database busy"},
+		{ResultCode: sqlite3.SQLITE_LOCKED, Code: meh.ErrUnavailable, Message: "database locked"},
+		{ResultCode: sqlite3.SQLITE_CANTOPEN, Code: meh.ErrUnavailable, Message: "cannot open database"},
+		{ResultCode: sqlite3.SQLITE_FULL, Code: meh.ErrUnavailable, Message: "database full"},
+	}
+}
+
+var (
+	// rules are the rules used in Classifier.
+	rules = DefaultRules()
+	// rulesMutex locks rules.
+	rulesMutex sync.RWMutex
+)
+
+// SetRules sets the rules to use in Classifier. A rule for the extended result
+// code takes precedence over one for the primary result code. Errors without
+// a matching rule result in meh.ErrInternal. In order to extend the defaults,
+// append to the ones from DefaultRules.
+func SetRules(newRules []Rule) {
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+	rules = append([]Rule(nil), newRules...)
+}
+
+// classifyResultCode returns the rule for the given extended result code. If no
+// rule matches, false is returned.
+func classifyResultCode(resultCode int) (Rule, bool) {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+	// The primary result code is held in the least significant 8 bits.
+	primaryResultCode := resultCode & 0xff
+	var primaryRule Rule
+	foundPrimary := false
+	for _, rule := range rules {
+		if rule.ResultCode == resultCode {
+			return rule, true
+		}
+		if !foundPrimary && rule.ResultCode == primaryResultCode {
+			primaryRule = rule
+			foundPrimary = true
+		}
+	}
+	return primaryRule, foundPrimary
+}
+
+// Classifier is a mehsql.Classifier for errors of type *sqlite.Error. The
+// meh.Code is determined using the rules set via SetRules. Per default,
+// constraint violations and data exceptions result in meh.ErrBadInput, unique
+// violations in meh.ErrConflict, interrupted queries in meh.ErrCanceled and
+// busy or locked databases in meh.ErrUnavailable. Otherwise, meh.ErrInternal.
+// Register it via mehsql.RegisterClassifier.
+var Classifier mehsql.Classifier = mehsql.ClassifierFunc(classify)
+
+// classify implements Classifier.
+func classify(err error) (mehsql.Classification, bool) {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return mehsql.Classification{}, false
+	}
+	details := meh.Details{DetailKeyCode: sqliteErr.Code()}
+	if codeName, ok := sqlite.ErrorCodeString[sqliteErr.Code()]; ok {
+		details[DetailKeyCodeName] = codeName
+	}
+	rule, ok := classifyResultCode(sqliteErr.Code())
+	if !ok {
+		return mehsql.Classification{
+			Code:    meh.ErrInternal,
+			Details: details,
+		}, true
+	}
+	return mehsql.Classification{
+		Code:    rule.Code,
+		Message: rule.Message,
+		Details: details,
+	}, true
+}
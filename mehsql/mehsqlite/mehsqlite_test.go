@@ -0,0 +1,98 @@
+package mehsqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehsql"
+	"github.com/stretchr/testify/suite"
+	_ "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"testing"
+)
+
+// ClassifierSuite tests Classifier with an in-memory SQLite database.
+type ClassifierSuite struct {
+	suite.Suite
+	db               *sql.DB
+	removeClassifier func()
+	ctx              context.Context
+	cancelCtx        context.CancelFunc
+}
+
+func (suite *ClassifierSuite) SetupTest() {
+	var err error
+	suite.db, err = sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
+	suite.Require().NoError(err, "open should not fail")
+	suite.db.SetMaxOpenConns(1)
+	suite.ctx, suite.cancelCtx = context.WithCancel(context.Background())
+	_, err = suite.db.ExecContext(suite.ctx, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id));
+		INSERT INTO users (id, email) VALUES (1, 'a@b.c');`)
+	suite.Require().NoError(err, "setup should not fail")
+	suite.removeClassifier = mehsql.RegisterClassifier(Classifier)
+}
+
+func (suite *ClassifierSuite) TearDownTest() {
+	suite.removeClassifier()
+	suite.cancelCtx()
+	_ = suite.db.Close()
+	SetRules(DefaultRules())
+}
+
+// execErr executes the given query and returns the error from
+// mehsql.NewQueryErr.
+func (suite *ClassifierSuite) execErr(query string, args ...any) *meh.Error {
+	_, err := suite.db.ExecContext(suite.ctx, query, args...)
+	suite.Require().Error(err, "exec should fail")
+	return mehsql.NewQueryErr(err, "exec", query, args...).(*meh.Error)
+}
+
+func (suite *ClassifierSuite) TestUniqueViolation() {
+	err := suite.execErr(`INSERT INTO users (email) VALUES ('a@b.c')`)
+	suite.Equal(meh.ErrConflict, meh.ErrorCode(err))
+	suite.Equal(sqlite3.SQLITE_CONSTRAINT_UNIQUE, err.Details[DetailKeyCode])
+	suite.Equal("unique violation", err.WrappedErr.(*meh.Error).Message)
+}
+
+func (suite *ClassifierSuite) TestPrimaryKeyViolation() {
+	err := suite.execErr(`INSERT INTO users (id, email) VALUES (1, 'd@e.f')`)
+	suite.Equal(meh.ErrConflict, meh.ErrorCode(err))
+}
+
+func (suite *ClassifierSuite) TestForeignKeyViolation() {
+	err := suite.execErr(`INSERT INTO posts (user_id) VALUES (2)`)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err))
+	suite.Equal("foreign key violation", err.WrappedErr.(*meh.Error).Message)
+}
+
+func (suite *ClassifierSuite) TestNotNullViolation() {
+	err := suite.execErr(`INSERT INTO users (email) VALUES (NULL)`)
+	suite.Equal(meh.ErrBadInput, meh.ErrorCode(err), "should fall back to primary result code")
+	suite.Equal("constraint violation", err.WrappedErr.(*meh.Error).Message)
+}
+
+func (suite *ClassifierSuite) TestSyntaxError() {
+	err := suite.execErr(`SELEC 1`)
+	suite.Equal(meh.ErrInternal, meh.ErrorCode(err))
+	suite.Equal(sqlite3.SQLITE_ERROR, err.Details[DetailKeyCode])
+	suite.Contains(err.Details[DetailKeyCodeName], "SQLITE_ERROR")
+}
+
+func (suite *ClassifierSuite) TestNoRows() {
+	var email string
+	queryErr := suite.db.QueryRowContext(suite.ctx, `SELECT email FROM users WHERE id = 2`).Scan(&email)
+	err := mehsql.NewQueryErr(queryErr, "query", "SELECT")
+	suite.Equal(meh.ErrNotFound, meh.ErrorCode(err), "should use default classifier")
+}
+
+func (suite *ClassifierSuite) TestCustomRules() {
+	SetRules([]Rule{{ResultCode: sqlite3.SQLITE_CONSTRAINT, Code: meh.ErrForbidden}})
+	err := suite.execErr(`INSERT INTO users (email) VALUES ('a@b.c')`)
+	suite.Equal(meh.ErrForbidden, meh.ErrorCode(err))
+}
+
+func TestClassifier(t *testing.T) {
+	suite.Run(t, new(ClassifierSuite))
+}
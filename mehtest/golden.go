@@ -0,0 +1,68 @@
+package mehtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden write
+// golden files instead of comparing against them if set to a non-empty value.
+// An environment variable is used instead of a flag, so that it works in any
+// package and does not conflict with flags of the tested program.
+const UpdateGoldenEnv = "MEHTEST_UPDATE"
+
+// updateGolden checks whether golden files should be updated. See
+// UpdateGoldenEnv.
+func updateGolden() bool {
+	return os.Getenv(UpdateGoldenEnv) != ""
+}
+
+// Serialize returns the serialized JSON representation of the given error as
+// used in AssertGolden. Stack traces are stripped using StripStackTraces as they
+// depend on the environment.
+func Serialize(err error) ([]byte, error) {
+	serialized, jsonErr := json.MarshalIndent(StripStackTraces(err), "", "  ")
+	if jsonErr != nil {
+		return nil, fmt.Errorf("marshal error: %w", jsonErr)
+	}
+	return append(serialized, '\n'), nil
+}
+
+// AssertGolden asserts that the serialized form of the given error (see
+// Serialize) equals the content of the golden file with the given name in the
+// testdata-directory. If the tests are run with the environment variable
+// UpdateGoldenEnv set, the golden file is written instead.
+func AssertGolden(t testing.TB, err error, name string) bool {
+	t.Helper()
+	serialized, serializeErr := Serialize(err)
+	if serializeErr != nil {
+		t.Fatalf("serialize error: %s", serializeErr.Error())
+		return false
+	}
+	goldenFilename := filepath.Join("testdata", name+".golden")
+	if updateGolden() {
+		mkdirErr := os.MkdirAll(filepath.Dir(goldenFilename), 0755)
+		if mkdirErr != nil {
+			t.Fatalf("create testdata directory: %s", mkdirErr.Error())
+			return false
+		}
+		writeErr := os.WriteFile(goldenFilename, serialized, 0644)
+		if writeErr != nil {
+			t.Fatalf("write golden file %q: %s", goldenFilename, writeErr.Error())
+			return false
+		}
+		return true
+	}
+	expected, readErr := os.ReadFile(goldenFilename)
+	if readErr != nil {
+		t.Fatalf("read golden file %q (run with %s=1 to create it): %s", goldenFilename, UpdateGoldenEnv,
+			readErr.Error())
+		return false
+	}
+	return assert.Equal(t, string(expected), string(serialized), "serialized error should match golden file %q",
+		goldenFilename)
+}
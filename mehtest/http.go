@@ -0,0 +1,59 @@
+package mehtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+)
+
+// AssertResponseCode asserts that the given httptest.ResponseRecorder recorded
+// the HTTP status code that mehhttp maps the given meh.Code to (see
+// mehhttp.SetHTTPStatusCodeMapping).
+func AssertResponseCode(t assert.TestingT, expected meh.Code, rr *httptest.ResponseRecorder,
+	msgAndArgs ...interface{}) bool {
+	helper(t)
+	expectedStatus := mehhttp.HTTPStatusCode(&meh.Error{Code: expected})
+	if expectedStatus != rr.Code {
+		return assert.Fail(t, fmt.Sprintf("Response status %d does not match %d for code %q",
+			rr.Code, expectedStatus, expected), msgAndArgs...)
+	}
+	return true
+}
+
+// AssertRespondedError asserts that the given httptest.ResponseRecorder
+// recorded a response as written by mehhttp.LogAndRespondError for the given
+// error. This checks the status code, the response body not leaking the error
+// message and the response matching the one of the ResponseRenderer set via
+// mehhttp.SetResponseRenderer (see mehhttp.RenderResponse). For JSON
+// responses, the code, message id and violations of mehhttp.JSONResponse are
+// compared, as translated messages depend on the request. Other bodies must be
+// equal.
+func AssertRespondedError(t assert.TestingT, err error, rr *httptest.ResponseRecorder,
+	msgAndArgs ...interface{}) bool {
+	helper(t)
+	status := mehhttp.HTTPStatusCode(err)
+	ok := assert.Equal(t, status, rr.Code, msgAndArgs...)
+	if err != nil && err.Error() != "" {
+		ok = assert.NotContains(t, rr.Body.String(), err.Error(), msgAndArgs...) && ok
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	contentType, expectedBody := mehhttp.RenderResponse(req, err, status)
+	ok = assert.Equal(t, contentType, rr.Header().Get("Content-Type"), msgAndArgs...) && ok
+	var expectedJSON mehhttp.JSONResponse
+	if json.Unmarshal(expectedBody, &expectedJSON) != nil {
+		return assert.Equal(t, string(expectedBody), rr.Body.String(), msgAndArgs...) && ok
+	}
+	var actualJSON mehhttp.JSONResponse
+	if unmarshalErr := json.Unmarshal(bytes.TrimSpace(rr.Body.Bytes()), &actualJSON); unmarshalErr != nil {
+		return assert.Fail(t, fmt.Sprintf("Response body is no JSON response: %s", unmarshalErr.Error()),
+			msgAndArgs...)
+	}
+	// Translated messages depend on the request, so we only compare the rest.
+	expectedJSON.Message = actualJSON.Message
+	return assert.Equal(t, expectedJSON, actualJSON, msgAndArgs...) && ok
+}
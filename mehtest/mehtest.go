@@ -0,0 +1,185 @@
+// Package mehtest provides assertion helpers for testing code that returns
+// meh.Error.
+package mehtest
+
+import (
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/stretchr/testify/assert"
+)
+
+// tHelper is implemented by testing.T and testing.B for marking helper
+// functions.
+type tHelper interface {
+	Helper()
+}
+
+// helper marks the calling function as test helper if supported by the given
+// assert.TestingT.
+func helper(t assert.TestingT) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+}
+
+// AssertCode asserts that meh.ErrorCode for the given error equals the
+// expected meh.Code.
+func AssertCode(t assert.TestingT, expected meh.Code, err error, msgAndArgs ...interface{}) bool {
+	helper(t)
+	if !assert.Error(t, err, msgAndArgs...) {
+		return false
+	}
+	return assert.Equal(t, expected, meh.ErrorCode(err), msgAndArgs...)
+}
+
+// AssertCodeAtLevel asserts that the error at the given level has the expected
+// meh.Code. Unlike AssertCode, this does not skip meh.ErrNeutral. Levels are
+// counted like in meh.ErrorUnwrapper, starting with 0 for the top-level error.
+func AssertCodeAtLevel(t assert.TestingT, expected meh.Code, err error, level int,
+	msgAndArgs ...interface{}) bool {
+	helper(t)
+	e, ok := errorAtLevel(t, err, level, msgAndArgs...)
+	if !ok {
+		return false
+	}
+	return assert.Equal(t, expected, meh.Cast(e).Code, msgAndArgs...)
+}
+
+// AssertMessage asserts that the complete error message, as returned by
+// Error.Error, equals the expected one.
+func AssertMessage(t assert.TestingT, expected string, err error, msgAndArgs ...interface{}) bool {
+	helper(t)
+	if !assert.Error(t, err, msgAndArgs...) {
+		return false
+	}
+	return assert.Equal(t, expected, err.Error(), msgAndArgs...)
+}
+
+// AssertMessageAtLevel asserts that the error at the given level has the
+// expected message. For meh.Error this is Error.Message and for any other error
+// the result of its Error-method.
+func AssertMessageAtLevel(t assert.TestingT, expected string, err error, level int,
+	msgAndArgs ...interface{}) bool {
+	helper(t)
+	e, ok := errorAtLevel(t, err, level, msgAndArgs...)
+	if !ok {
+		return false
+	}
+	message := e.Error()
+	if mehErr, ok := e.(*meh.Error); ok {
+		message = mehErr.Message
+	}
+	return assert.Equal(t, expected, message, msgAndArgs...)
+}
+
+// AssertDetail asserts that any level of the given error holds a detail with
+// the given key and expected value. If multiple levels hold the key, it
+// suffices if one of them matches.
+func AssertDetail(t assert.TestingT, err error, key string, expected interface{},
+	msgAndArgs ...interface{}) bool {
+	helper(t)
+	if !assert.Error(t, err, msgAndArgs...) {
+		return false
+	}
+	found := make([]interface{}, 0)
+	for it := meh.NewErrorUnwrapper(err); it.Next(); {
+		e, ok := it.Current().(*meh.Error)
+		if !ok {
+			continue
+		}
+		v, ok := e.Details[key]
+		if !ok {
+			continue
+		}
+		if assert.ObjectsAreEqual(expected, v) {
+			return true
+		}
+		found = append(found, v)
+	}
+	if len(found) == 0 {
+		return assert.Fail(t, fmt.Sprintf("Detail %q not found in any level", key), msgAndArgs...)
+	}
+	return assert.Fail(t, fmt.Sprintf("Detail %q has unexpected value:\n"+
+		"expected: %#v\n"+
+		"found   : %#v", key, expected, found), msgAndArgs...)
+}
+
+// AssertDetailAtLevel asserts that the error at the given level holds a detail
+// with the given key and expected value.
+func AssertDetailAtLevel(t assert.TestingT, err error, level int, key string, expected interface{},
+	msgAndArgs ...interface{}) bool {
+	helper(t)
+	e, ok := errorAtLevel(t, err, level, msgAndArgs...)
+	if !ok {
+		return false
+	}
+	mehErr, ok := e.(*meh.Error)
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("Error at level %d is no meh error but %T", level, e), msgAndArgs...)
+	}
+	v, ok := mehErr.Details[key]
+	if !ok {
+		return assert.Fail(t, fmt.Sprintf("Detail %q not found in level %d", key, level), msgAndArgs...)
+	}
+	return assert.Equal(t, expected, v, msgAndArgs...)
+}
+
+// AssertNoDetail asserts that no level of the given error holds a detail with
+// the given key.
+func AssertNoDetail(t assert.TestingT, err error, key string, msgAndArgs ...interface{}) bool {
+	helper(t)
+	for it := meh.NewErrorUnwrapper(err); it.Next(); {
+		e, ok := it.Current().(*meh.Error)
+		if !ok {
+			continue
+		}
+		if v, ok := e.Details[key]; ok {
+			return assert.Fail(t, fmt.Sprintf("Detail %q unexpectedly found in level %d with value %#v",
+				key, it.Level(), v), msgAndArgs...)
+		}
+	}
+	return true
+}
+
+// errorAtLevel returns the error at the given level. If the level does not
+// exist, the test fails.
+func errorAtLevel(t assert.TestingT, err error, level int, msgAndArgs ...interface{}) (error, bool) {
+	helper(t)
+	if !assert.Error(t, err, msgAndArgs...) {
+		return nil, false
+	}
+	it := meh.NewErrorUnwrapper(err)
+	for it.Next() {
+		if it.Level() == level {
+			return it.Current(), true
+		}
+	}
+	return nil, assert.Fail(t, fmt.Sprintf("Level %d not found in error with %d levels", level, it.Level()),
+		msgAndArgs...)
+}
+
+// StripStackTraces returns a copy of the given error with the stack traces of
+// all meh.Error levels removed. The given error is not altered. Only the chain
+// of meh.Error levels is followed: the first wrapped error that is no
+// meh.Error is kept as it is, including any meh.Error it wraps itself, like
+// via fmt.Errorf with %w, as such errors cannot be copied generically.
+func StripStackTraces(err error) error {
+	e, ok := err.(*meh.Error)
+	if !ok {
+		return err
+	}
+	return &meh.Error{
+		Code:                  e.Code,
+		WrappedErr:            StripStackTraces(e.WrappedErr),
+		WrappedErrPassThrough: e.WrappedErrPassThrough,
+		Message:               e.Message,
+		Details:               e.Details,
+	}
+}
+
+// AssertEqual asserts that both errors are deeply equal while ignoring stack
+// traces.
+func AssertEqual(t assert.TestingT, expected error, actual error, msgAndArgs ...interface{}) bool {
+	helper(t)
+	return assert.Equal(t, StripStackTraces(expected), StripStackTraces(actual), msgAndArgs...)
+}
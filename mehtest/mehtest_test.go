@@ -0,0 +1,253 @@
+package mehtest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehhttp"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testingTStub records failures reported via assert.TestingT.
+type testingTStub struct {
+	failures []string
+}
+
+func (t *testingTStub) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+// assertionsSuite tests the assertion helpers.
+type assertionsSuite struct {
+	suite.Suite
+	t   *testingTStub
+	err error
+}
+
+func (suite *assertionsSuite) SetupTest() {
+	suite.t = &testingTStub{}
+	suite.err = meh.Wrap(meh.NewNotFoundErrFromErr(errors.New("sad life"), "get user", meh.Details{
+		"user_id": 42,
+	}), "handle request", meh.Details{"method": "GET"})
+}
+
+func (suite *assertionsSuite) TestCodeOK() {
+	suite.True(AssertCode(suite.t, meh.ErrNotFound, suite.err))
+	suite.Empty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestCodeMismatch() {
+	suite.False(AssertCode(suite.t, meh.ErrInternal, suite.err))
+	suite.NotEmpty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestCodeNilError() {
+	suite.False(AssertCode(suite.t, meh.ErrInternal, nil))
+	suite.NotEmpty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestCodeAtLevel() {
+	suite.True(AssertCodeAtLevel(suite.t, meh.ErrNeutral, suite.err, 0))
+	suite.True(AssertCodeAtLevel(suite.t, meh.ErrNotFound, suite.err, 1))
+	suite.Empty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestCodeAtLevelOutOfRange() {
+	suite.False(AssertCodeAtLevel(suite.t, meh.ErrNotFound, suite.err, 3))
+	suite.NotEmpty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestMessage() {
+	suite.True(AssertMessage(suite.t, "handle request: get user: sad life", suite.err))
+	suite.False(AssertMessage(suite.t, "get user", suite.err))
+	suite.Len(suite.t.failures, 1)
+}
+
+func (suite *assertionsSuite) TestMessageAtLevel() {
+	suite.True(AssertMessageAtLevel(suite.t, "get user", suite.err, 1))
+	suite.True(AssertMessageAtLevel(suite.t, "sad life", suite.err, 2))
+	suite.Empty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestDetail() {
+	suite.True(AssertDetail(suite.t, suite.err, "user_id", 42))
+	suite.True(AssertDetail(suite.t, suite.err, "method", "GET"))
+	suite.Empty(suite.t.failures)
+}
+
+func (suite *assertionsSuite) TestDetailMismatch() {
+	suite.False(AssertDetail(suite.t, suite.err, "user_id", 43))
+	suite.False(AssertDetail(suite.t, suite.err, "unknown", 43))
+	suite.Len(suite.t.failures, 2)
+}
+
+func (suite *assertionsSuite) TestDetailAtLevel() {
+	suite.True(AssertDetailAtLevel(suite.t, suite.err, 1, "user_id", 42))
+	suite.False(AssertDetailAtLevel(suite.t, suite.err, 0, "user_id", 42))
+	suite.False(AssertDetailAtLevel(suite.t, suite.err, 2, "user_id", 42))
+	suite.Len(suite.t.failures, 2)
+}
+
+func (suite *assertionsSuite) TestNoDetail() {
+	suite.True(AssertNoDetail(suite.t, suite.err, "unknown"))
+	suite.False(AssertNoDetail(suite.t, suite.err, "user_id"))
+	suite.Len(suite.t.failures, 1)
+}
+
+func TestAssertions(t *testing.T) {
+	suite.Run(t, new(assertionsSuite))
+}
+
+// AssertEqualSuite tests AssertEqual.
+type AssertEqualSuite struct {
+	suite.Suite
+}
+
+func (suite *AssertEqualSuite) TestIgnoreStackTraces() {
+	t := &testingTStub{}
+	expected := meh.Wrap(meh.NewInternalErr("sad life", meh.Details{"a": "b"}), "outer", nil)
+	actual := meh.Wrap(meh.ApplyStackTrace(meh.NewInternalErr("sad life", meh.Details{"a": "b"})), "outer", nil)
+	suite.True(AssertEqual(t, expected, actual))
+	suite.Empty(t.failures)
+}
+
+func (suite *AssertEqualSuite) TestMismatch() {
+	t := &testingTStub{}
+	expected := meh.NewInternalErr("sad life", meh.Details{"a": "b"})
+	actual := meh.NewInternalErr("sad life", meh.Details{"a": "c"})
+	suite.False(AssertEqual(t, expected, actual))
+	suite.NotEmpty(t.failures)
+}
+
+func (suite *AssertEqualSuite) TestNotAltered() {
+	e := meh.ApplyStackTrace(meh.NewInternalErr("sad life", nil)).(*meh.Error)
+	_ = StripStackTraces(e)
+	suite.NotEmpty(e.Trace.StackTrace, "should not alter original error")
+}
+
+func TestAssertEqual(t *testing.T) {
+	suite.Run(t, new(AssertEqualSuite))
+}
+
+// TestAssertGolden tests AssertGolden.
+func TestAssertGolden(t *testing.T) {
+	err := meh.Wrap(meh.ApplyStackTrace(meh.NewBadInputErrFromErr(errors.New("sad life"), "parse", meh.Details{
+		"input": "meow",
+	})), "handle request", meh.Details{"method": "GET"})
+	AssertGolden(t, err, "golden")
+}
+
+// TestAssertGoldenUpdate assures that AssertGolden writes golden files if
+// UpdateGoldenEnv is set.
+func TestAssertGoldenUpdate(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, meh.NewNotFoundErr("sad life", nil), "update")
+	expected, err := Serialize(meh.NewNotFoundErr("sad life", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(filepath.Join("testdata", "update.golden"))
+	if err != nil {
+		t.Fatalf("should write golden file: %s", err.Error())
+	}
+	if string(written) != string(expected) {
+		t.Errorf("golden file should contain serialized error, got %q", written)
+	}
+}
+
+// httpAssertionsSuite tests AssertResponseCode and AssertRespondedError.
+type httpAssertionsSuite struct {
+	suite.Suite
+	t  *testingTStub
+	rr *httptest.ResponseRecorder
+}
+
+func (suite *httpAssertionsSuite) SetupTest() {
+	suite.t = &testingTStub{}
+	suite.rr = httptest.NewRecorder()
+	mehhttp.SetHTTPStatusCodeMapping(func(code meh.Code) int {
+		switch code {
+		case meh.ErrNotFound:
+			return http.StatusNotFound
+		default:
+			return http.StatusInternalServerError
+		}
+	})
+}
+
+func (suite *httpAssertionsSuite) TearDownTest() {
+	mehhttp.SetHTTPStatusCodeMapping(func(_ meh.Code) int {
+		return http.StatusInternalServerError
+	})
+}
+
+func (suite *httpAssertionsSuite) TestResponseCode() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	mehhttp.LogAndRespondError(zap.NewNop(), suite.rr, req, meh.NewNotFoundErr("sad life", nil))
+	suite.True(AssertResponseCode(suite.t, meh.ErrNotFound, suite.rr))
+	suite.False(AssertResponseCode(suite.t, meh.ErrInternal, suite.rr))
+	suite.Len(suite.t.failures, 1)
+}
+
+func (suite *httpAssertionsSuite) TestRespondedError() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	err := meh.NewNotFoundErr("sad life", nil)
+	mehhttp.LogAndRespondError(zap.NewNop(), suite.rr, req, err)
+	suite.True(AssertRespondedError(suite.t, err, suite.rr))
+	suite.Empty(suite.t.failures)
+}
+
+func (suite *httpAssertionsSuite) TestRespondedErrorLeak() {
+	err := meh.NewNotFoundErr("sad life", nil)
+	suite.rr.WriteHeader(http.StatusNotFound)
+	_, _ = suite.rr.WriteString(err.Error())
+	suite.False(AssertRespondedError(suite.t, err, suite.rr))
+	suite.NotEmpty(suite.t.failures)
+}
+
+func (suite *httpAssertionsSuite) TestRespondedErrorJSON() {
+	mehhttp.SetResponseRenderer(mehhttp.JSONResponseRenderer)
+	defer mehhttp.SetResponseRenderer(mehhttp.EmptyResponseRenderer)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	err := meh.NewNotFoundErr("sad life", nil)
+	mehhttp.LogAndRespondError(zap.NewNop(), suite.rr, req, err)
+	suite.True(AssertRespondedError(suite.t, err, suite.rr))
+	suite.Empty(suite.t.failures)
+}
+
+func (suite *httpAssertionsSuite) TestRespondedErrorWrongBody() {
+	mehhttp.SetResponseRenderer(mehhttp.JSONResponseRenderer)
+	defer mehhttp.SetResponseRenderer(mehhttp.EmptyResponseRenderer)
+	suite.rr.Header().Set("Content-Type", "application/json")
+	suite.rr.WriteHeader(http.StatusNotFound)
+	_, _ = suite.rr.WriteString(`{"code":"internal"}`)
+	suite.False(AssertRespondedError(suite.t, meh.NewNotFoundErr("sad life", nil), suite.rr))
+	suite.NotEmpty(suite.t.failures)
+}
+
+func (suite *httpAssertionsSuite) TestRespondedErrorEmptyBody() {
+	mehhttp.SetResponseRenderer(mehhttp.JSONResponseRenderer)
+	defer mehhttp.SetResponseRenderer(mehhttp.EmptyResponseRenderer)
+	suite.rr.Header().Set("Content-Type", "application/json")
+	suite.rr.WriteHeader(http.StatusNotFound)
+	suite.False(AssertRespondedError(suite.t, meh.NewNotFoundErr("sad life", nil), suite.rr))
+	suite.NotEmpty(suite.t.failures)
+}
+
+func TestHTTPAssertions(t *testing.T) {
+	suite.Run(t, new(httpAssertionsSuite))
+}
@@ -0,0 +1,106 @@
+// Package mehzerolog allows logging of meh.Error to zerolog.Logger with the
+// same semantics and field names as mehlog.
+package mehzerolog
+
+import (
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehlog"
+	"github.com/rs/zerolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+)
+
+var (
+	// defaultLevelTranslator is the default LevelTranslator that translates every
+	// meh.Code to zerolog.ErrorLevel.
+	defaultLevelTranslator LevelTranslator = func(code meh.Code) zerolog.Level {
+		return zerolog.ErrorLevel
+	}
+	// defaultLevelTranslatorMutex locks defaultLevelTranslator.
+	defaultLevelTranslatorMutex sync.RWMutex
+)
+
+// SetDefaultLevelTranslator sets the LevelTranslator to be used for regular
+// Log-calls.
+func SetDefaultLevelTranslator(lt LevelTranslator) {
+	defaultLevelTranslatorMutex.Lock()
+	defer defaultLevelTranslatorMutex.Unlock()
+	defaultLevelTranslator = lt
+}
+
+// LevelTranslator translates the given meh.Code to zerolog.Level for logging.
+type LevelTranslator func(code meh.Code) zerolog.Level
+
+// WrapAndLog calls Log after meh.Wrap with the given error and message.
+func WrapAndLog(logger *zerolog.Logger, err error, message string) {
+	Log(logger, meh.Wrap(err, message, nil))
+}
+
+// Log the given error using the default level translator that can be set via
+// SetDefaultLevelTranslator.
+func Log(logger *zerolog.Logger, err error) {
+	e := meh.Cast(err)
+	defaultLevelTranslatorMutex.RLock()
+	level := defaultLevelTranslator(meh.ErrorCode(e))
+	defaultLevelTranslatorMutex.RUnlock()
+	LogToLevel(logger, level, err)
+}
+
+// LogToLevel logs the given error to the given zerolog.Level. It uses
+// mehlog.LogToEmitter, so that fields, hooks and sampling are the same as in
+// mehlog and respect settings like mehlog.UseNestedErrorField or
+// mehlog.SetStackTraceConfig.
+func LogToLevel(logger *zerolog.Logger, level zerolog.Level, err error) {
+	mehlog.LogToEmitter(func(message string, fields []zap.Field) {
+		event := eventForLevel(logger, level)
+		keys, values := mehlog.FieldValues(fields)
+		for _, k := range keys {
+			event = event.Interface(k, values[k])
+		}
+		event.Msg(message)
+	}, zapLevel(level), err)
+}
+
+// zapLevel returns the zapcore.Level that matches the given zerolog.Level. It
+// is passed to hooks and used for deciding about stack traces in mehlog.
+func zapLevel(level zerolog.Level) zapcore.Level {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return zapcore.DebugLevel
+	case zerolog.InfoLevel:
+		return zapcore.InfoLevel
+	case zerolog.WarnLevel:
+		return zapcore.WarnLevel
+	case zerolog.PanicLevel:
+		return zapcore.PanicLevel
+	case zerolog.FatalLevel:
+		return zapcore.FatalLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// eventForLevel creates the zerolog.Event for the given zerolog.Level. Like in
+// mehlog, zerolog.PanicLevel panics and zerolog.FatalLevel exits after logging
+// while unknown levels are logged to zerolog.ErrorLevel.
+func eventForLevel(logger *zerolog.Logger, level zerolog.Level) *zerolog.Event {
+	switch level {
+	case zerolog.TraceLevel:
+		return logger.Trace()
+	case zerolog.DebugLevel:
+		return logger.Debug()
+	case zerolog.InfoLevel:
+		return logger.Info()
+	case zerolog.WarnLevel:
+		return logger.Warn()
+	case zerolog.ErrorLevel:
+		return logger.Error()
+	case zerolog.PanicLevel:
+		return logger.Panic()
+	case zerolog.FatalLevel:
+		return logger.Fatal()
+	default:
+		return logger.Error()
+	}
+}
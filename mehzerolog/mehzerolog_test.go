@@ -0,0 +1,154 @@
+package mehzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/lefinal/meh"
+	"github.com/lefinal/meh/mehlog"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+)
+
+// LogSuite tests Log.
+type LogSuite struct {
+	suite.Suite
+	buf    *bytes.Buffer
+	logger *zerolog.Logger
+}
+
+func (suite *LogSuite) SetupTest() {
+	suite.buf = &bytes.Buffer{}
+	logger := zerolog.New(suite.buf).Level(zerolog.TraceLevel)
+	suite.logger = &logger
+}
+
+// records parses the logged JSON lines.
+func (suite *LogSuite) records() []map[string]interface{} {
+	records := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(suite.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		suite.Require().NoError(json.Unmarshal([]byte(line), &record), "should log valid json")
+		records = append(records, record)
+	}
+	return records
+}
+
+func (suite *LogSuite) TestNilError() {
+	Log(suite.logger, nil)
+	suite.Len(suite.records(), 1, "should be logged")
+}
+
+func (suite *LogSuite) TestNonMehError() {
+	Log(suite.logger, errors.New("sad life"))
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.Equal("error", records[0][zerolog.LevelFieldName])
+	suite.Equal("sad life", records[0][zerolog.MessageFieldName])
+}
+
+func (suite *LogSuite) TestDetails() {
+	Log(suite.logger, meh.Wrap(&meh.Error{Code: meh.ErrNotFound, Details: meh.Details{"hello": "world"}}, "ola",
+		meh.Details{"i_love": "cookies"}))
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.Equal("world", records[0]["1/hello"], "should contain details from root error")
+	suite.Equal("cookies", records[0]["0/i_love"], "should contain details from wrapper")
+	suite.Equal(string(meh.ErrNotFound), records[0][meh.MapFieldErrorCode], "should contain code")
+	suite.Equal("ola", records[0][meh.MapFieldErrorMessage], "should contain error message")
+}
+
+func (suite *LogSuite) TestOmitErrorMessageField() {
+	mehlog.OmitErrorMessageField(true)
+	defer mehlog.OmitErrorMessageField(false)
+	Log(suite.logger, meh.NewInternalErr("sad life", nil))
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.NotContains(records[0], meh.MapFieldErrorMessage, "should respect mehlog settings")
+}
+
+func (suite *LogSuite) TestLevelTranslator() {
+	SetDefaultLevelTranslator(func(code meh.Code) zerolog.Level {
+		if code == meh.ErrNotFound {
+			return zerolog.DebugLevel
+		}
+		return zerolog.ErrorLevel
+	})
+	defer SetDefaultLevelTranslator(func(_ meh.Code) zerolog.Level {
+		return zerolog.ErrorLevel
+	})
+	Log(suite.logger, meh.NewNotFoundErr("sad life", nil))
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.Equal("debug", records[0][zerolog.LevelFieldName])
+}
+
+func (suite *LogSuite) TestWrapAndLog() {
+	WrapAndLog(suite.logger, &meh.Error{Message: "inner"}, "outer")
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.Equal("outer: inner", records[0][zerolog.MessageFieldName])
+}
+
+func (suite *LogSuite) TestLevels() {
+	for _, level := range []zerolog.Level{zerolog.TraceLevel, zerolog.DebugLevel, zerolog.InfoLevel,
+		zerolog.WarnLevel, zerolog.ErrorLevel} {
+		suite.buf.Reset()
+		LogToLevel(suite.logger, level, meh.NewInternalErr("sad life", nil))
+		records := suite.records()
+		suite.Require().Len(records, 1, "should be logged")
+		suite.Equal(level.String(), records[0][zerolog.LevelFieldName])
+	}
+}
+
+func (suite *LogSuite) TestPanicLevel() {
+	suite.Panics(func() {
+		LogToLevel(suite.logger, zerolog.PanicLevel, meh.NewInternalErr("sad life", nil))
+	})
+}
+
+func (suite *LogSuite) TestUnknownLevel() {
+	LogToLevel(suite.logger, zerolog.Level(42), meh.NewInternalErr("sad life", nil))
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.Equal("error", records[0][zerolog.LevelFieldName])
+}
+
+func (suite *LogSuite) TestNestedErrorField() {
+	mehlog.UseNestedErrorField(true)
+	defer mehlog.UseNestedErrorField(false)
+	Log(suite.logger, meh.NewInternalErr("sad life", meh.Details{"hello": "world"}))
+	records := suite.records()
+	suite.Require().Len(records, 1, "should be logged")
+	suite.Contains(records[0], mehlog.FieldError, "should log nested error field")
+	suite.NotContains(records[0], "0/hello", "should not spread details")
+}
+
+func (suite *LogSuite) TestHooks() {
+	var level zapcore.Level
+	removeHook := mehlog.AddHook(func(_ error, l zapcore.Level) {
+		level = l
+	})
+	defer removeHook()
+	LogToLevel(suite.logger, zerolog.WarnLevel, meh.NewInternalErr("sad life", nil))
+	suite.Equal(zapcore.WarnLevel, level, "should call hooks with matching level")
+}
+
+func (suite *LogSuite) TestSampler() {
+	mehlog.SetSampler(mehlog.NewSampler(mehlog.SamplingConfig{First: 1}))
+	defer mehlog.SetSampler(nil)
+	for i := 0; i < 3; i++ {
+		Log(suite.logger, meh.NewInternalErr("sad life", nil))
+	}
+	suite.Len(suite.records(), 1, "should suppress repeated errors")
+}
+
+func TestLog(t *testing.T) {
+	suite.Run(t, new(LogSuite))
+}
@@ -0,0 +1,192 @@
+package meh
+
+import (
+	"bytes"
+	"encoding/json"
+	"golang.org/x/text/language"
+	"io"
+	"sync"
+	"text/template"
+)
+
+// DetailKeyPublicMessage is the details key for the PublicMessage added via
+// WithPublicMessage. Use PublicMessageOf in order to retrieve it from an error.
+const DetailKeyPublicMessage = "public_message"
+
+// PublicMessage is a message that is safe to be shown to clients. Instead of
+// fixed text, it holds an ID and parameters, so that it can be translated using
+// a Catalog.
+type PublicMessage struct {
+	// ID identifies the message in a Catalog like "user-not-found".
+	ID string `json:"id"`
+	// Params are optional parameters for the message template.
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// WithPublicMessage adds a PublicMessage with the given ID and params to the
+// given error. The internal error message is not changed.
+func WithPublicMessage(err error, id string, params map[string]any) error {
+	return ApplyDetails(err, Details{
+		DetailKeyPublicMessage: PublicMessage{
+			ID:     id,
+			Params: params,
+		},
+	})
+}
+
+// PublicMessageOf returns the PublicMessage from the highest level of the given
+// error that has one. This also works for errors that were unmarshalled from
+// JSON. If no PublicMessage is found, false is returned.
+func PublicMessageOf(err error) (PublicMessage, bool) {
+	for it := NewErrorUnwrapper(err); it.Next(); {
+		e, ok := it.Current().(*Error)
+		if !ok {
+			continue
+		}
+		if message, ok := detailAs[PublicMessage](e.Details[DetailKeyPublicMessage]); ok {
+			return message, true
+		}
+	}
+	return PublicMessage{}, false
+}
+
+// detailAs returns the given details value as T. If the value is not of type T,
+// for example, because of unmarshalling from JSON, it is converted using its
+// JSON representation.
+func detailAs[T any](detail any) (T, bool) {
+	var t T
+	if detail == nil {
+		return t, false
+	}
+	if t, ok := detail.(T); ok {
+		return t, true
+	}
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return t, false
+	}
+	if json.Unmarshal(raw, &t) != nil {
+		return t, false
+	}
+	return t, true
+}
+
+// Catalog holds translations of PublicMessage for multiple languages. Messages
+// are text/template templates that are executed with PublicMessage.Params as
+// data, like "User {{.name}} not found". Create one using NewCatalog.
+type Catalog struct {
+	// fallback is the language to use if no other one matches.
+	fallback language.Tag
+	// tags are all languages with messages. The first one is always fallback.
+	tags []language.Tag
+	// matcher matches requested languages against tags.
+	matcher language.Matcher
+	// messages holds the message templates by language and message ID.
+	messages map[language.Tag]map[string]*template.Template
+	// m locks all fields.
+	m sync.RWMutex
+}
+
+// NewCatalog creates a new Catalog that uses the given fallback language if
+// requested languages do not match or messages are not translated.
+func NewCatalog(fallback language.Tag) *Catalog {
+	return &Catalog{
+		fallback: fallback,
+		tags:     []language.Tag{fallback},
+		matcher:  language.NewMatcher([]language.Tag{fallback}),
+		messages: map[language.Tag]map[string]*template.Template{
+			fallback: make(map[string]*template.Template),
+		},
+	}
+}
+
+// Add adds the message template with the given ID for the given language. An
+// existing one is replaced.
+func (c *Catalog) Add(lang language.Tag, id string, messageTemplate string) error {
+	return c.Load(lang, map[string]string{id: messageTemplate})
+}
+
+// Load adds the given message templates by ID for the given language. Existing
+// ones are replaced. If any template is invalid, none is added.
+func (c *Catalog) Load(lang language.Tag, messageTemplates map[string]string) error {
+	parsed := make(map[string]*template.Template, len(messageTemplates))
+	for id, messageTemplate := range messageTemplates {
+		t, err := template.New(id).Option("missingkey=error").Parse(messageTemplate)
+		if err != nil {
+			return NewBadInputErrFromErr(err, "parse message template", Details{
+				"lang":     lang.String(),
+				"id":       id,
+				"template": messageTemplate,
+			})
+		}
+		parsed[id] = t
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	messages, ok := c.messages[lang]
+	if !ok {
+		messages = make(map[string]*template.Template)
+		c.messages[lang] = messages
+		c.tags = append(c.tags, lang)
+		c.matcher = language.NewMatcher(c.tags)
+	}
+	for id, t := range parsed {
+		messages[id] = t
+	}
+	return nil
+}
+
+// LoadJSON reads a JSON object with message IDs as keys and message templates
+// as values from the given io.Reader and loads it for the given language using
+// Load.
+func (c *Catalog) LoadJSON(lang language.Tag, r io.Reader) error {
+	var messageTemplates map[string]string
+	err := json.NewDecoder(r).Decode(&messageTemplates)
+	if err != nil {
+		return NewBadInputErrFromErr(err, "decode json", Details{"lang": lang.String()})
+	}
+	err = c.Load(lang, messageTemplates)
+	if err != nil {
+		return Wrap(err, "load", nil)
+	}
+	return nil
+}
+
+// Match returns the language from the Catalog that matches best for the given
+// Accept-Language header value. If none matches, the fallback language is
+// returned.
+func (c *Catalog) Match(acceptLanguage string) language.Tag {
+	requested, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(requested) == 0 {
+		return c.fallback
+	}
+	c.m.RLock()
+	defer c.m.RUnlock()
+	_, index, confidence := c.matcher.Match(requested...)
+	if confidence == language.No {
+		return c.fallback
+	}
+	return c.tags[index]
+}
+
+// Translate returns the translation of the given PublicMessage for the given
+// language. If the message is not available in the language, the fallback
+// language is used. If it is not available at all or executing the template
+// fails, false is returned.
+func (c *Catalog) Translate(lang language.Tag, message PublicMessage) (string, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	t, ok := c.messages[lang][message.ID]
+	if !ok {
+		t, ok = c.messages[c.fallback][message.ID]
+		if !ok {
+			return "", false
+		}
+	}
+	var b bytes.Buffer
+	err := t.Execute(&b, message.Params)
+	if err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
@@ -0,0 +1,118 @@
+package meh
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/language"
+	"strings"
+	"testing"
+)
+
+// PublicMessageOfSuite tests WithPublicMessage and PublicMessageOf.
+type PublicMessageOfSuite struct {
+	suite.Suite
+}
+
+func (suite *PublicMessageOfSuite) TestNone() {
+	_, ok := PublicMessageOf(NewNotFoundErr("meh", nil))
+	suite.False(ok, "should not find public message")
+}
+
+func (suite *PublicMessageOfSuite) TestHighestLevel() {
+	err := WithPublicMessage(NewNotFoundErr("meh", nil), "inner", nil)
+	err = Wrap(WithPublicMessage(err, "outer", map[string]any{"id": 1}), "wrap", nil)
+	message, ok := PublicMessageOf(err)
+	suite.Require().True(ok, "should find public message")
+	suite.Equal(PublicMessage{ID: "outer", Params: map[string]any{"id": 1}}, message)
+	suite.Equal(ErrNotFound, ErrorCode(err), "should keep code")
+	suite.Equal("wrap: meh", err.Error(), "should keep internal message")
+}
+
+func (suite *PublicMessageOfSuite) TestJSONRoundTrip() {
+	raw, err := json.Marshal(WithPublicMessage(NewNotFoundErr("meh", nil), "user-not-found", map[string]any{"name": "meh"}))
+	suite.Require().NoError(err, "marshal should not fail")
+	var e *Error
+	suite.Require().NoError(json.Unmarshal(raw, &e), "unmarshal should not fail")
+	message, ok := PublicMessageOf(e)
+	suite.Require().True(ok, "should find public message")
+	suite.Equal(PublicMessage{ID: "user-not-found", Params: map[string]any{"name": "meh"}}, message)
+}
+
+func TestPublicMessageOf(t *testing.T) {
+	suite.Run(t, new(PublicMessageOfSuite))
+}
+
+// CatalogSuite tests Catalog.
+type CatalogSuite struct {
+	suite.Suite
+	c *Catalog
+}
+
+func (suite *CatalogSuite) SetupTest() {
+	suite.c = NewCatalog(language.English)
+	suite.Require().NoError(suite.c.Load(language.English, map[string]string{
+		"user-not-found": "User {{.name}} not found.",
+		"english-only":   "Only in English.",
+	}))
+	suite.Require().NoError(suite.c.LoadJSON(language.German, strings.NewReader(`{
+		"user-not-found": "Benutzer {{.name}} nicht gefunden."
+	}`)))
+}
+
+func (suite *CatalogSuite) TestMatch() {
+	tests := map[string]language.Tag{
+		"":                     language.English,
+		"de":                   language.German,
+		"de-AT":                language.German,
+		"fr, de;q=0.5":         language.German,
+		"en-US,en;q=0.9,de;q=": language.English,
+		"fr":                   language.English,
+		"not a language":       language.English,
+	}
+	for acceptLanguage, expect := range tests {
+		suite.Equal(expect, suite.c.Match(acceptLanguage), "should match %q", acceptLanguage)
+	}
+}
+
+func (suite *CatalogSuite) TestTranslate() {
+	message, ok := suite.c.Translate(language.German, PublicMessage{ID: "user-not-found", Params: map[string]any{"name": "meh"}})
+	suite.True(ok, "should translate")
+	suite.Equal("Benutzer meh nicht gefunden.", message)
+}
+
+func (suite *CatalogSuite) TestTranslateFallback() {
+	message, ok := suite.c.Translate(language.German, PublicMessage{ID: "english-only"})
+	suite.True(ok, "should translate")
+	suite.Equal("Only in English.", message, "should use fallback language")
+}
+
+func (suite *CatalogSuite) TestTranslateUnknown() {
+	_, ok := suite.c.Translate(language.German, PublicMessage{ID: "unknown"})
+	suite.False(ok, "should not translate")
+}
+
+func (suite *CatalogSuite) TestTranslateMissingParam() {
+	_, ok := suite.c.Translate(language.English, PublicMessage{ID: "user-not-found"})
+	suite.False(ok, "should not translate")
+}
+
+func (suite *CatalogSuite) TestAddReplaces() {
+	suite.Require().NoError(suite.c.Add(language.English, "english-only", "Replaced."))
+	message, _ := suite.c.Translate(language.English, PublicMessage{ID: "english-only"})
+	suite.Equal("Replaced.", message)
+}
+
+func (suite *CatalogSuite) TestLoadInvalidTemplate() {
+	err := suite.c.Load(language.French, map[string]string{"broken": "{{.name"})
+	suite.Equal(ErrBadInput, ErrorCode(err), "should fail")
+	suite.Equal(language.English, suite.c.Match("fr"), "should not add language")
+}
+
+func (suite *CatalogSuite) TestLoadJSONInvalid() {
+	err := suite.c.LoadJSON(language.French, strings.NewReader(`[]`))
+	suite.Equal(ErrBadInput, ErrorCode(err), "should fail")
+}
+
+func TestCatalog(t *testing.T) {
+	suite.Run(t, new(CatalogSuite))
+}
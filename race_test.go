@@ -0,0 +1,52 @@
+package meh
+
+import (
+	"encoding/json"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"testing"
+)
+
+// sharedErr is a sentinel error that is shared between goroutines in
+// TestConcurrentSharing.
+var sharedErr = NewNotFoundErrFromErr(unmarshallableErr{}, "shared", Details{
+	"nested":   Details{"a": 1},
+	"password": "hunter2",
+})
+
+// TestConcurrentSharing assures that a shared error can be used from several
+// goroutines without races. Run it with -race.
+func TestConcurrentSharing(t *testing.T) {
+	const goroutines = 16
+	const iterations = 50
+	shared := Wrap(ApplyStackTrace(sharedErr), "wrap", Details{"b": []any{1, os.ErrNotExist}})
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				_ = ToMap(shared)
+				if _, err := json.Marshal(shared); err != nil {
+					t.Errorf("marshal should not fail: %v", err)
+					return
+				}
+				if err := Cast(shared).MarshalLogObject(zapcore.NewMapObjectEncoder()); err != nil {
+					t.Errorf("marshal log object should not fail: %v", err)
+					return
+				}
+				_ = ApplyStackTrace(shared)
+				_ = ApplyStackTrace(sharedErr)
+				_ = Fingerprint(shared)
+				_ = Violations(shared)
+				_ = Cast(shared).Clone()
+				_ = shared.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(Cast(sharedErr).Details) != 2 || Cast(sharedErr).Trace.StackTrace != nil {
+		t.Error("should not alter shared error")
+	}
+}
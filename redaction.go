@@ -0,0 +1,344 @@
+package meh
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedValue is the replacement for values that are masked with
+// RedactionMask.
+const RedactedValue = "[redacted]"
+
+// Secret wraps a sensitive value in Details. It is masked by the default
+// redaction rules and never prints or marshals the wrapped value itself.
+type Secret struct {
+	// Value is the sensitive value.
+	Value any
+}
+
+// String returns RedactedValue.
+func (s Secret) String() string {
+	return RedactedValue
+}
+
+// GoString returns RedactedValue.
+func (s Secret) GoString() string {
+	return RedactedValue
+}
+
+// MarshalJSON marshals RedactedValue.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + RedactedValue + `"`), nil
+}
+
+// RedactionAction is the action for values matched by a RedactionRule.
+type RedactionAction int
+
+const (
+	// RedactionMask replaces the value with RedactedValue. This is the default.
+	RedactionMask RedactionAction = iota
+	// RedactionHash replaces the value with a truncated SHA-256 hash like
+	// "sha256:0123456789abcdef". This keeps values comparable. Note that the hash
+	// is unsalted, so values from small domains like emails or IDs can be
+	// recovered with a dictionary attack. Set a key via SetRedactionHashKey in
+	// order to use a keyed HMAC-SHA256 like "hmac-sha256:0123456789abcdef"
+	// instead.
+	RedactionHash
+	// RedactionDrop removes the value along with its key.
+	RedactionDrop
+)
+
+// RedactionRule matches values in Details that need to be redacted. If
+// multiple matchers are set, all of them must match. A rule without matchers
+// matches nothing.
+type RedactionRule struct {
+	// KeyGlob matches keys case-insensitively using path.Match syntax like
+	// "*token*". Unlike path.Match, "*" and "?" match "/" as well, so that
+	// "*token*" matches "auth/token".
+	KeyGlob string
+	// KeyRegexp matches keys.
+	KeyRegexp *regexp.Regexp
+	// ValueType matches values of the given type like the one of Secret or
+	// []byte.
+	ValueType reflect.Type
+	// Action is the RedactionAction to apply to matched values.
+	Action RedactionAction
+}
+
+// matches checks whether the RedactionRule matches the given key and value.
+func (rule RedactionRule) matches(key string, value any) bool {
+	if rule.KeyGlob == "" && rule.KeyRegexp == nil && rule.ValueType == nil {
+		return false
+	}
+	if rule.KeyGlob != "" && !matchKeyGlob(rule.KeyGlob, key) {
+		return false
+	}
+	if rule.KeyRegexp != nil && !rule.KeyRegexp.MatchString(key) {
+		return false
+	}
+	if rule.ValueType != nil && reflect.TypeOf(value) != rule.ValueType {
+		return false
+	}
+	return true
+}
+
+// matchKeyGlob matches the given key against the given glob for
+// RedactionRule.KeyGlob.
+func matchKeyGlob(glob string, key string) bool {
+	// path.Match does not match separators with wildcards, so we replace them
+	// with a character that is not special.
+	toMatch := func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "/", "\x00")
+	}
+	matched, _ := path.Match(toMatch(glob), toMatch(key))
+	return matched
+}
+
+// DefaultRedactionRules returns the default rules that mask Secret values as
+// well as values with keys that usually hold credentials like passwords,
+// tokens or authorization headers.
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{
+		{ValueType: reflect.TypeOf(Secret{})},
+		{KeyGlob: "*password*"},
+		{KeyGlob: "*token*"},
+		{KeyGlob: "*secret*"},
+		{KeyGlob: "*api_key*"},
+		{KeyGlob: "*authorization*"},
+		{KeyGlob: "*cookie*"},
+	}
+}
+
+var (
+	// redactionRules are the rules to use in RedactDetails.
+	redactionRules = DefaultRedactionRules()
+	// redactionRulesMutex locks redactionRules.
+	redactionRulesMutex sync.RWMutex
+)
+
+var (
+	// redactionHashKey is the key for RedactionHash. If empty, unkeyed SHA-256 is
+	// used.
+	redactionHashKey []byte
+	// redactionHashKeyMutex locks redactionHashKey.
+	redactionHashKeyMutex sync.RWMutex
+)
+
+// SetRedactionHashKey sets the secret key for RedactionHash. If set, values are
+// hashed using HMAC-SHA256 with the key, so that they cannot be recovered with
+// a dictionary attack without knowing the key. Hashes stay comparable as long
+// as the key is the same. Pass nil in order to use unkeyed SHA-256, which is
+// the default.
+func SetRedactionHashKey(key []byte) {
+	redactionHashKeyMutex.Lock()
+	defer redactionHashKeyMutex.Unlock()
+	redactionHashKey = append([]byte(nil), key...)
+}
+
+// SetRedactionRules sets the rules that are used in RedactDetails. The first
+// matching rule is applied. Per default, DefaultRedactionRules are used. Pass
+// nil in order to disable redaction.
+func SetRedactionRules(rules []RedactionRule) {
+	redactionRulesMutex.Lock()
+	defer redactionRulesMutex.Unlock()
+	redactionRules = append([]RedactionRule(nil), rules...)
+}
+
+// RedactDetails returns a copy of the given Details with the rules set via
+// SetRedactionRules applied. Values nested in maps with string keys like
+// http.Header, slices, arrays and exported struct fields are redacted as well.
+// Nested values with redacted content are replaced with maps and slices of
+// type any, where struct fields are keyed by their JSON names. Values
+// implementing json.Marshaler or encoding.TextMarshaler are not inspected.
+// Details with keys DetailKeyViolations and DetailKeyPublicMessage are not
+// redacted, so that their params keep working for translation. The given
+// Details are not altered. This is used in ToMap,
+// Error.MarshalJSON and Error.MarshalLogObject, so all logger integrations
+// apply redaction.
+func RedactDetails(details Details) Details {
+	if details == nil {
+		return nil
+	}
+	redactionRulesMutex.RLock()
+	defer redactionRulesMutex.RUnlock()
+	redacted, _ := redactDetails(redactionRules, details, 0)
+	return redacted
+}
+
+// maxRedactionDepth is the maximum depth of nested values that are inspected
+// in RedactDetails. This avoids endless recursion for cyclic values.
+const maxRedactionDepth = 32
+
+// redactDetails implements RedactDetails with the given rules. It also returns
+// whether any value was redacted.
+func redactDetails(rules []RedactionRule, details map[string]any, depth int) (Details, bool) {
+	redacted := make(Details, len(details))
+	changed := false
+	for k, v := range details {
+		if depth == 0 && isStructuredDetail(k) {
+			redacted[k] = v
+			continue
+		}
+		v, keep, valueChanged := redactValue(rules, k, v, depth)
+		changed = changed || valueChanged
+		if keep {
+			redacted[k] = v
+		}
+	}
+	return redacted, changed
+}
+
+// redactValue applies the first matching rule to the given value or redacts
+// nested values. If the value is dropped, keep is false. If anything was
+// redacted, changed is true.
+func redactValue(rules []RedactionRule, key string, value any, depth int) (redacted any, keep bool, changed bool) {
+	for _, rule := range rules {
+		if !rule.matches(key, value) {
+			continue
+		}
+		switch rule.Action {
+		case RedactionHash:
+			return hashValue(value), true, true
+		case RedactionDrop:
+			return nil, false, true
+		default:
+			return RedactedValue, true, true
+		}
+	}
+	if depth >= maxRedactionDepth {
+		return value, true, false
+	}
+	switch value := value.(type) {
+	case Details:
+		redacted, changed := redactDetails(rules, value, depth+1)
+		return redacted, true, changed
+	case map[string]any:
+		redacted, changed := redactDetails(rules, value, depth+1)
+		return map[string]any(redacted), true, changed
+	}
+	redacted, changed = redactNested(rules, key, reflect.ValueOf(value), depth+1)
+	if !changed {
+		return value, true, false
+	}
+	return redacted, true, true
+}
+
+var (
+	// jsonMarshalerType is the reflect.Type of json.Marshaler.
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	// textMarshalerType is the reflect.Type of encoding.TextMarshaler.
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isMarshaler checks whether the given reflect.Type implements json.Marshaler
+// or encoding.TextMarshaler.
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// redactNested redacts values nested in the given value using reflection.
+// Elements of slices and arrays are matched with the key of the slice. If
+// nothing was redacted, false is returned.
+func redactNested(rules []RedactionRule, key string, value reflect.Value, depth int) (any, bool) {
+	if !value.IsValid() {
+		return nil, false
+	}
+	if isMarshaler(value.Type()) || isMarshaler(reflect.PointerTo(value.Type())) {
+		return nil, false
+	}
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() || depth >= maxRedactionDepth {
+			return nil, false
+		}
+		return redactNested(rules, key, value.Elem(), depth+1)
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		redacted := make(map[string]any, value.Len())
+		changed := false
+		for it := value.MapRange(); it.Next(); {
+			v, keep, valueChanged := redactValue(rules, it.Key().String(), it.Value().Interface(), depth)
+			changed = changed || valueChanged
+			if keep {
+				redacted[it.Key().String()] = v
+			}
+		}
+		return redacted, changed
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are no containers.
+			return nil, false
+		}
+		redacted := make([]any, 0, value.Len())
+		changed := false
+		for i := 0; i < value.Len(); i++ {
+			v, keep, valueChanged := redactValue(rules, key, value.Index(i).Interface(), depth)
+			changed = changed || valueChanged
+			if keep {
+				redacted = append(redacted, v)
+			}
+		}
+		return redacted, changed
+	case reflect.Struct:
+		redacted := make(map[string]any, value.NumField())
+		changed := false
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				tagName, _, _ := strings.Cut(tag, ",")
+				if tagName == "-" {
+					continue
+				}
+				if tagName != "" {
+					name = tagName
+				}
+			}
+			v, keep, valueChanged := redactValue(rules, name, value.Field(i).Interface(), depth)
+			changed = changed || valueChanged
+			if keep {
+				redacted[name] = v
+			}
+		}
+		return redacted, changed
+	}
+	return nil, false
+}
+
+// hashValue returns the truncated SHA-256 hash or the HMAC-SHA256 with the key
+// set via SetRedactionHashKey of the given value for RedactionHash.
+func hashValue(value any) string {
+	var raw []byte
+	switch value := value.(type) {
+	case []byte:
+		raw = value
+	case Secret:
+		raw = []byte(fmt.Sprintf("%v", value.Value))
+	default:
+		raw = []byte(fmt.Sprintf("%v", value))
+	}
+	redactionHashKeyMutex.RLock()
+	key := redactionHashKey
+	redactionHashKeyMutex.RUnlock()
+	if len(key) == 0 {
+		sum := sha256.Sum256(raw)
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(raw)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,205 @@
+package meh
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// RedactDetailsSuite tests RedactDetails and SetRedactionRules.
+type RedactDetailsSuite struct {
+	suite.Suite
+}
+
+func (suite *RedactDetailsSuite) TearDownTest() {
+	SetRedactionRules(DefaultRedactionRules())
+	SetRedactionHashKey(nil)
+}
+
+func (suite *RedactDetailsSuite) TestNil() {
+	suite.Nil(RedactDetails(nil))
+}
+
+func (suite *RedactDetailsSuite) TestDefault() {
+	details := Details{
+		"user_id":       42,
+		"Password":      "hunter2",
+		"refresh_token": "abc",
+		"Authorization": "Bearer abc",
+		"user":          Secret{Value: "bob"},
+		"nested": map[string]any{
+			"api_key": "abc",
+			"name":    "meh",
+		},
+	}
+	suite.Equal(Details{
+		"user_id":       42,
+		"Password":      RedactedValue,
+		"refresh_token": RedactedValue,
+		"Authorization": RedactedValue,
+		"user":          RedactedValue,
+		"nested": map[string]any{
+			"api_key": RedactedValue,
+			"name":    "meh",
+		},
+	}, RedactDetails(details))
+	suite.Equal("hunter2", details["Password"], "should not alter original details")
+}
+
+func (suite *RedactDetailsSuite) TestHTTPHeader() {
+	header := http.Header{
+		"Authorization": []string{"Bearer abc"},
+		"Accept":        []string{"application/json"},
+	}
+	suite.Equal(Details{
+		"header": map[string]any{
+			"Authorization": RedactedValue,
+			"Accept":        []string{"application/json"},
+		},
+	}, RedactDetails(Details{"header": header}))
+	suite.Equal("Bearer abc", header.Get("Authorization"), "should not alter original header")
+}
+
+func (suite *RedactDetailsSuite) TestNested() {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+		internal string
+	}
+	suite.Equal(Details{
+		"strings": map[string]any{"token": RedactedValue, "name": "meh"},
+		"list":    []any{map[string]any{"password": RedactedValue}, 42},
+		"values":  []any{RedactedValue},
+		"struct":  map[string]any{"user": "bob", "password": RedactedValue},
+		"pointer": map[string]any{"user": "bob", "password": RedactedValue},
+		"plain":   []int{1, 2},
+	}, RedactDetails(Details{
+		"strings": map[string]string{"token": "abc", "name": "meh"},
+		"list":    []any{map[string]any{"password": "hunter2"}, 42},
+		"values":  []Secret{{Value: "abc"}},
+		"struct":  credentials{User: "bob", Password: "hunter2", internal: "meh"},
+		"pointer": &credentials{User: "bob", Password: "hunter2"},
+		"plain":   []int{1, 2},
+	}))
+}
+
+func (suite *RedactDetailsSuite) TestCyclic() {
+	type node struct {
+		Next *node `json:"next"`
+	}
+	n := &node{}
+	n.Next = n
+	suite.NotPanics(func() {
+		RedactDetails(Details{"node": n})
+	})
+}
+
+func (suite *RedactDetailsSuite) TestActions() {
+	SetRedactionRules([]RedactionRule{
+		{KeyRegexp: regexp.MustCompile(`^email$`), Action: RedactionHash},
+		{ValueType: reflect.TypeOf([]byte(nil)), Action: RedactionDrop},
+		{KeyGlob: "card_*", ValueType: reflect.TypeOf(""), Action: RedactionMask},
+	})
+	redacted := RedactDetails(Details{
+		"email":       "bob@example.com",
+		"body":        []byte("{}"),
+		"card_number": "4111",
+		"card_count":  2,
+	})
+	suite.Equal(Details{
+		"email":       hashValue("bob@example.com"),
+		"card_number": RedactedValue,
+		"card_count":  2,
+	}, redacted)
+	suite.True(strings.HasPrefix(hashValue("bob@example.com"), "sha256:"), "should prefix hash")
+	suite.Equal(hashValue("bob@example.com"), RedactDetails(Details{"email": "bob@example.com"})["email"],
+		"should hash consistently")
+}
+
+func (suite *RedactDetailsSuite) TestHashKey() {
+	SetRedactionRules([]RedactionRule{{KeyGlob: "email", Action: RedactionHash}})
+	unkeyed := RedactDetails(Details{"email": "bob@example.com"})["email"]
+	SetRedactionHashKey([]byte("key"))
+	keyed := RedactDetails(Details{"email": "bob@example.com"})["email"]
+	suite.NotEqual(unkeyed, keyed, "should use key")
+	suite.True(strings.HasPrefix(keyed.(string), "hmac-sha256:"), "should prefix hmac")
+	suite.Equal(keyed, RedactDetails(Details{"email": "bob@example.com"})["email"], "should hash consistently")
+	SetRedactionHashKey([]byte("other"))
+	suite.NotEqual(keyed, RedactDetails(Details{"email": "bob@example.com"})["email"], "should depend on key")
+}
+
+func (suite *RedactDetailsSuite) TestKeyGlobMatchesSlash() {
+	suite.Equal(Details{"auth/token": RedactedValue}, RedactDetails(Details{"auth/token": "abc"}))
+}
+
+func (suite *RedactDetailsSuite) TestKeepsStructuredDetails() {
+	err := WithPublicMessage(NewBadInputErr("meh", Details{"token": "abc"}), "invalid_token", map[string]any{
+		"token": "abc",
+	})
+	raw, marshalErr := json.Marshal(err)
+	suite.Require().NoError(marshalErr, "marshal should not fail")
+	var unmarshalled *Error
+	suite.Require().NoError(json.Unmarshal(raw, &unmarshalled), "unmarshal should not fail")
+	message, ok := PublicMessageOf(unmarshalled)
+	suite.Require().True(ok, "should find public message")
+	suite.Equal("abc", message.Params["token"], "should not redact params of public message")
+	tokens := make([]any, 0)
+	for it := NewErrorUnwrapper(unmarshalled); it.Next(); {
+		if token, ok := Cast(it.Current()).Details["token"]; ok {
+			tokens = append(tokens, token)
+		}
+	}
+	suite.Equal([]any{RedactedValue}, tokens, "should still redact other details")
+}
+
+func (suite *RedactDetailsSuite) TestEmptyRuleMatchesNothing() {
+	SetRedactionRules([]RedactionRule{{Action: RedactionDrop}})
+	suite.Equal(Details{"a": 1}, RedactDetails(Details{"a": 1}))
+}
+
+func (suite *RedactDetailsSuite) TestDisabled() {
+	SetRedactionRules(nil)
+	suite.Equal(Details{"password": "hunter2"}, RedactDetails(Details{"password": "hunter2"}))
+}
+
+func (suite *RedactDetailsSuite) TestToMap() {
+	m := ToMap(Wrap(NewInternalErr("meh", Details{"password": "hunter2"}), "wrap", Details{"token": "abc"}))
+	suite.Equal(RedactedValue, m["0/token"])
+	suite.Equal(RedactedValue, m["1/password"])
+}
+
+func (suite *RedactDetailsSuite) TestMarshalJSON() {
+	raw, err := json.Marshal(Wrap(NewInternalErr("meh", Details{"password": "hunter2"}), "wrap", nil))
+	suite.Require().NoError(err, "should not fail")
+	suite.NotContains(string(raw), "hunter2", "should redact details")
+}
+
+func (suite *RedactDetailsSuite) TestMarshalLogObject() {
+	enc := zapcore.NewMapObjectEncoder()
+	suite.Require().NoError(Cast(NewInternalErr("meh", Details{"password": "hunter2"})).MarshalLogObject(enc))
+	suite.NotContains(fmt.Sprintf("%v", enc.Fields), "hunter2", "should redact details")
+}
+
+func TestRedactDetails(t *testing.T) {
+	suite.Run(t, new(RedactDetailsSuite))
+}
+
+// TestSecret assures that Secret never prints or marshals the wrapped value.
+func TestSecret(t *testing.T) {
+	s := Secret{Value: "hunter2"}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{fmt.Sprint(s), fmt.Sprintf("%+v", s), fmt.Sprintf("%#v", s), string(raw)} {
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("should not contain secret: %s", out)
+		}
+	}
+}
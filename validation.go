@@ -0,0 +1,112 @@
+package meh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DetailKeyViolations is the details key for the list of Violation added by
+// ValidationError. Use Violations in order to retrieve them from an error.
+const DetailKeyViolations = "violations"
+
+// Violation describes a violation of a single field in input data.
+type Violation struct {
+	// Path is the path of the field like "address.street" or "items[3].price".
+	// Use FieldPath for building it. It is empty if the violation applies to the
+	// whole input.
+	Path string `json:"path,omitempty"`
+	// Constraint is the machine-readable name of the violated constraint like
+	// "required" or "max".
+	Constraint string `json:"constraint"`
+	// Message is an optional message that is safe to be shown to clients.
+	Message string `json:"message,omitempty"`
+	// Params are optional parameters of the constraint like the maximum value for
+	// "max".
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// ValidationError collects Violation and creates an ErrBadInput error from
+// them via Err. Create one using NewValidationError.
+type ValidationError struct {
+	violations []Violation
+}
+
+// NewValidationError creates a new ValidationError without violations.
+func NewValidationError() *ValidationError {
+	return &ValidationError{
+		violations: make([]Violation, 0),
+	}
+}
+
+// Add adds a Violation with the given path, constraint, public message and
+// params.
+func (v *ValidationError) Add(path string, constraint string, message string, params map[string]any) *ValidationError {
+	return v.AddViolation(Violation{
+		Path:       path,
+		Constraint: constraint,
+		Message:    message,
+		Params:     params,
+	})
+}
+
+// AddViolation adds the given Violation.
+func (v *ValidationError) AddViolation(violation Violation) *ValidationError {
+	v.violations = append(v.violations, violation)
+	return v
+}
+
+// HasViolations returns true if at least one Violation was added.
+func (v *ValidationError) HasViolations() bool {
+	return len(v.violations) > 0
+}
+
+// Violations returns a copy of the added violations.
+func (v *ValidationError) Violations() []Violation {
+	return append([]Violation(nil), v.violations...)
+}
+
+// Err returns nil if no violations were added. Otherwise, an ErrBadInput error
+// with the violations in details with key DetailKeyViolations is returned.
+func (v *ValidationError) Err() error {
+	if !v.HasViolations() {
+		return nil
+	}
+	return NewBadInputErr("validation failed", Details{
+		DetailKeyViolations: v.Violations(),
+	})
+}
+
+// Violations returns all Violation from details with key DetailKeyViolations of
+// all levels of the given error. This also works for errors that were
+// unmarshalled from JSON.
+func Violations(err error) []Violation {
+	violations := make([]Violation, 0)
+	for it := NewErrorUnwrapper(err); it.Next(); {
+		e, ok := it.Current().(*Error)
+		if !ok {
+			continue
+		}
+		levelViolations, _ := detailAs[[]Violation](e.Details[DetailKeyViolations])
+		violations = append(violations, levelViolations...)
+	}
+	return violations
+}
+
+// FieldPath builds the path for a Violation from the given segments. String
+// segments are joined with dots and integer segments are used as indices. For
+// example, FieldPath("items", 3, "price") returns "items[3].price".
+func FieldPath(segments ...any) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		switch segment := segment.(type) {
+		case int:
+			_, _ = fmt.Fprintf(&b, "[%d]", segment)
+		default:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			_, _ = fmt.Fprintf(&b, "%v", segment)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package meh
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+// ValidationErrorSuite tests ValidationError and Violations.
+type ValidationErrorSuite struct {
+	suite.Suite
+	v *ValidationError
+}
+
+func (suite *ValidationErrorSuite) SetupTest() {
+	suite.v = NewValidationError().
+		Add(FieldPath("items", 3, "price"), "min", "must be at least 1", map[string]any{"min": 1}).
+		AddViolation(Violation{Path: "name", Constraint: "required"})
+}
+
+func (suite *ValidationErrorSuite) TestNoViolations() {
+	v := NewValidationError()
+	suite.False(v.HasViolations(), "should have no violations")
+	suite.NoError(v.Err(), "should return nil")
+}
+
+func (suite *ValidationErrorSuite) TestErr() {
+	err := suite.v.Err()
+	suite.True(suite.v.HasViolations(), "should have violations")
+	suite.Equal(ErrBadInput, ErrorCode(err), "should return bad input")
+	suite.Equal([]Violation{
+		{Path: "items[3].price", Constraint: "min", Message: "must be at least 1", Params: map[string]any{"min": 1}},
+		{Path: "name", Constraint: "required"},
+	}, Violations(err))
+}
+
+func (suite *ValidationErrorSuite) TestWrapped() {
+	err := Wrap(suite.v.Err(), "validate", Details{
+		DetailKeyViolations: []Violation{{Path: "id", Constraint: "uuid"}},
+	})
+	suite.Len(Violations(err), 3, "should collect violations from all levels")
+}
+
+func (suite *ValidationErrorSuite) TestJSONRoundTrip() {
+	raw, err := json.Marshal(Wrap(suite.v.Err(), "validate", nil))
+	suite.Require().NoError(err, "marshal should not fail")
+	var e *Error
+	suite.Require().NoError(json.Unmarshal(raw, &e), "unmarshal should not fail")
+	suite.Equal(ErrBadInput, ErrorCode(e), "should keep code")
+	suite.Equal([]Violation{
+		{Path: "items[3].price", Constraint: "min", Message: "must be at least 1", Params: map[string]any{"min": float64(1)}},
+		{Path: "name", Constraint: "required"},
+	}, Violations(e))
+}
+
+func (suite *ValidationErrorSuite) TestToMap() {
+	m := ToMap(suite.v.Err())
+	suite.Equal(suite.v.Violations(), m["0/"+DetailKeyViolations], "should include violations")
+}
+
+func TestValidationError(t *testing.T) {
+	suite.Run(t, new(ValidationErrorSuite))
+}
+
+// TestViolationsInvalid tests Violations for invalid details.
+func TestViolationsInvalid(t *testing.T) {
+	assert.Empty(t, Violations(NewBadInputErr("meh", Details{DetailKeyViolations: "invalid"})))
+}
+
+// TestFieldPath tests FieldPath.
+func TestFieldPath(t *testing.T) {
+	tests := map[string][]any{
+		"":               nil,
+		"name":           {"name"},
+		"address.street": {"address", "street"},
+		"items[3].price": {"items", 3, "price"},
+		"[0][1]":         {0, 1},
+	}
+	for expect, segments := range tests {
+		if got := FieldPath(segments...); got != expect {
+			t.Errorf("FieldPath(%v) = %q, want %q", segments, got, expect)
+		}
+	}
+}
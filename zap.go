@@ -0,0 +1,103 @@
+package meh
+
+import (
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"sort"
+)
+
+// Keys used in Error.MarshalLogObject.
+const (
+	LogObjectKeyCode       = "code"
+	LogObjectKeyMessage    = "message"
+	LogObjectKeyLevels     = "levels"
+	LogObjectKeyType       = "type"
+	LogObjectKeyDetails    = "details"
+	LogObjectKeyStackTrace = "stacktrace"
+)
+
+// MarshalLogObject implements zapcore.ObjectMarshaler. The object holds the
+// effective Code (see ErrorCode), the complete error message, each level with
+// its own Code, Message and Details as well as the deepest stack trace if one
+// was applied using ApplyStackTrace. Details are redacted using RedactDetails
+// and sorted by key in order to provide a deterministic output. Limits set via
+// SetLimits are applied. Wrapped errors that are no Error hold their type and
+// message.
+func (e *Error) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString(LogObjectKeyCode, string(ErrorCode(e)))
+	enc.AddString(LogObjectKeyMessage, LimitMessage(e.Error()))
+	// The total size budget for details is spent from the deepest level up like in
+	// ToMap.
+	levels := make([]logObjectLevel, 0)
+	for it := NewErrorUnwrapper(e); it.Next(); {
+		levels = append(levels, logObjectLevel{err: it.Current()})
+	}
+	budget := currentLimits().MaxTotalSize
+	for i := len(levels) - 1; i >= 0; i-- {
+		if levelErr, ok := levels[i].err.(*Error); ok && len(levelErr.Details) > 0 {
+			levels[i].details = prepareDetails(levelErr.Details, &budget)
+		}
+	}
+	err := enc.AddArray(LogObjectKeyLevels, zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, level := range levels {
+			err := arr.AppendObject(level)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+	if stackTrace := e.StackTrace(); stackTrace != nil {
+		enc.AddString(LogObjectKeyStackTrace, fmt.Sprintf("%+v", stackTrace))
+	}
+	return nil
+}
+
+// logObjectLevel is a zapcore.ObjectMarshaler for a single level in
+// Error.MarshalLogObject.
+type logObjectLevel struct {
+	err error
+	// details are the prepared details of err.
+	details Details
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (level logObjectLevel) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	e, ok := level.err.(*Error)
+	if !ok {
+		enc.AddString(LogObjectKeyType, fmt.Sprintf("%T", level.err))
+		enc.AddString(LogObjectKeyMessage, LimitMessage(level.err.Error()))
+		return nil
+	}
+	enc.AddString(LogObjectKeyCode, string(e.Code))
+	if e.Message != "" {
+		enc.AddString(LogObjectKeyMessage, LimitMessage(e.Message))
+	}
+	if len(level.details) == 0 {
+		return nil
+	}
+	return enc.AddObject(LogObjectKeyDetails, logObjectDetails(level.details))
+}
+
+// logObjectDetails is a zapcore.ObjectMarshaler for Details that adds them
+// sorted by key.
+type logObjectDetails Details
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (details logObjectDetails) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := enc.AddReflected(k, details[k]); err != nil {
+			// Fallback to native representation if the value cannot be encoded.
+			enc.AddString(k, fmt.Sprintf("%+v", details[k]))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package meh
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+// ErrorMarshalLogObjectSuite tests Error.MarshalLogObject.
+type ErrorMarshalLogObjectSuite struct {
+	suite.Suite
+	err *Error
+}
+
+func (suite *ErrorMarshalLogObjectSuite) SetupTest() {
+	suite.err = Wrap(NewNotFoundErrFromErr(errors.New("sad life"), "get user", Details{
+		"user_id": 42,
+		"name":    "meow",
+	}), "handle request", nil).(*Error)
+}
+
+func (suite *ErrorMarshalLogObjectSuite) TestMapEncoder() {
+	enc := zapcore.NewMapObjectEncoder()
+	suite.Require().NoError(suite.err.MarshalLogObject(enc))
+	suite.Equal(string(ErrNotFound), enc.Fields[LogObjectKeyCode])
+	suite.Equal(suite.err.Error(), enc.Fields[LogObjectKeyMessage])
+	suite.Equal([]interface{}{
+		map[string]interface{}{
+			LogObjectKeyCode:    string(ErrNeutral),
+			LogObjectKeyMessage: "handle request",
+		},
+		map[string]interface{}{
+			LogObjectKeyCode:    string(ErrNotFound),
+			LogObjectKeyMessage: "get user",
+			LogObjectKeyDetails: map[string]interface{}{
+				"user_id": 42,
+				"name":    "meow",
+			},
+		},
+		map[string]interface{}{
+			LogObjectKeyType:    "*errors.errorString",
+			LogObjectKeyMessage: "sad life",
+		},
+	}, enc.Fields[LogObjectKeyLevels])
+	suite.NotContains(enc.Fields, LogObjectKeyStackTrace, "should not add stack trace if not set")
+}
+
+func (suite *ErrorMarshalLogObjectSuite) TestDeterministicJSON() {
+	encode := func() string {
+		enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+		buf, err := enc.EncodeEntry(zapcore.Entry{Time: time.Time{}}, []zapcore.Field{
+			{Key: "error", Type: zapcore.ObjectMarshalerType, Interface: suite.err},
+		})
+		suite.Require().NoError(err, "encode should not fail")
+		return buf.String()
+	}
+	expected := `{"error":{"code":"not-found","message":"handle request: get user: sad life","levels":[` +
+		`{"code":"neutral","message":"handle request"},` +
+		`{"code":"not-found","message":"get user","details":{"name":"meow","user_id":42}},` +
+		`{"type":"*errors.errorString","message":"sad life"}]}}` + "\n"
+	for i := 0; i < 10; i++ {
+		suite.Require().Equal(expected, encode(), "should encode deterministically")
+	}
+}
+
+func (suite *ErrorMarshalLogObjectSuite) TestStackTrace() {
+	e := Wrap(ApplyStackTrace(NewInternalErr("sad life", nil)), "outer", nil).(*Error)
+	enc := zapcore.NewMapObjectEncoder()
+	suite.Require().NoError(e.MarshalLogObject(enc))
+	suite.NotEmpty(enc.Fields[LogObjectKeyStackTrace], "should add stack trace")
+}
+
+func (suite *ErrorMarshalLogObjectSuite) TestUnencodableDetail() {
+	e := NewInternalErr("sad life", Details{"ch": make(chan int)}).(*Error)
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{
+		{Key: "error", Type: zapcore.ObjectMarshalerType, Interface: e},
+	})
+	suite.Require().NoError(err, "encode should not fail")
+	suite.Contains(buf.String(), `"ch":"0x`, "should fall back to native representation")
+}
+
+func TestError_MarshalLogObject(t *testing.T) {
+	suite.Run(t, new(ErrorMarshalLogObjectSuite))
+}